@@ -0,0 +1,361 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ArchiveSeparator separates the path of an archive (a jar, zip, wheel, ...)
+// from the path of an entry inside it, e.g. guava-33.0-sources.jar!/com/google/common/base/Strings.java
+const ArchiveSeparator = "!/"
+
+// ArchivePath returns the virtual path of an entry in an archive.
+func ArchivePath(archive string, entry string) string {
+	return archive + ArchiveSeparator + entry
+}
+
+// SplitArchivePath splits a virtual path into the archive and the entry, and
+// reports whether the path refers to an entry in an archive.
+func SplitArchivePath(path string) (string, string, bool) {
+	archive, entry, ok := strings.Cut(path, ArchiveSeparator)
+	if !ok || archive == "" || entry == "" {
+		return path, "", false
+	}
+	return archive, entry, true
+}
+
+// Archives come from third-party repositories, so only entries within these
+// budgets are read (the sizes are checked against the data as it's read).
+const (
+	maxArchiveEntries   = 200_000
+	maxArchiveSize      = 2 << 30
+	maxArchiveEntrySize = 64 << 20
+)
+
+// SkippedEntry is an archive entry that was not read, because it is unsafe or
+// over the archive's budget.
+type SkippedEntry struct {
+	Archive string
+	Entry   string
+	Reason  string
+}
+
+// openArchive is an archive that stays open between reads. Entries are read
+// under the read lock, so workers can read the same archive in parallel, and
+// the archive is loaded and closed under the write lock.
+type openArchive struct {
+	sync.RWMutex
+	path     string
+	lastUsed uint64
+	err      error
+	closed   bool
+	reader   *zip.ReadCloser
+	files    []*zip.File
+	entries  map[string]*zip.File
+	skipped  map[string]string
+}
+
+// Archives stay open between reads, since files are read one entry at a time
+const maxOpenArchives = 16
+
+// archives is locked only to find open archives, not to read them.
+var archives = struct {
+	sync.Mutex
+	open    map[string]*openArchive
+	skipped map[string][]SkippedEntry
+	// clock orders the archives by their last use
+	clock uint64
+}{
+	open:    make(map[string]*openArchive),
+	skipped: make(map[string][]SkippedEntry),
+}
+
+// checkEntry returns the reason an entry can't be read, given the number and
+// total size of the entries before it, or "" if it is safe to read.
+func checkEntry(f *zip.File, count int, size uint64) string {
+	switch {
+	case !filepath.IsLocal(filepath.FromSlash(f.Name)) || strings.Contains(f.Name, `\`):
+		return "unsafe path"
+	case f.Mode()&fs.ModeSymlink != 0:
+		return "symlink"
+	case !f.Mode().IsRegular():
+		return "not a regular file"
+	case f.UncompressedSize64 > maxArchiveEntrySize:
+		return "entry too large"
+	case count >= maxArchiveEntries:
+		return "too many entries"
+	case size+f.UncompressedSize64 > maxArchiveSize:
+		return "archive too large"
+	default:
+		return ""
+	}
+}
+
+// load reads the central directory of the archive.
+func (a *openArchive) load() error {
+	reader, err := zip.OpenReader(a.path)
+	if err != nil {
+		return err
+	}
+	a.reader = reader
+	a.entries = make(map[string]*zip.File)
+	a.skipped = make(map[string]string)
+	var size uint64
+	skipped := make([]SkippedEntry, 0)
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if reason := checkEntry(f, len(a.files), size); reason != "" {
+			a.skipped[f.Name] = reason
+			skipped = append(skipped, SkippedEntry{Archive: a.path, Entry: f.Name, Reason: reason})
+			continue
+		}
+		size += f.UncompressedSize64
+		a.files = append(a.files, f)
+		a.entries[f.Name] = f
+	}
+	archives.Lock()
+	archives.skipped[a.path] = skipped
+	archives.Unlock()
+	return nil
+}
+
+// close closes the archive once the reads in progress are done.
+func (a *openArchive) close() {
+	a.Lock()
+	defer a.Unlock()
+	if a.reader != nil && !a.closed {
+		a.reader.Close()
+	}
+	a.closed = true
+}
+
+// SkippedArchiveEntries returns the entries that were skipped in the archives
+// read so far.
+func SkippedArchiveEntries() []SkippedEntry {
+	archives.Lock()
+	defer archives.Unlock()
+	paths := make([]string, 0, len(archives.skipped))
+	for path := range archives.skipped {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	acc := make([]SkippedEntry, 0)
+	for _, path := range paths {
+		acc = append(acc, archives.skipped[path]...)
+	}
+	return acc
+}
+
+// lookup returns an entry that is safe to read.
+func (a *openArchive) lookup(op string, archive string, entry string) (*zip.File, error) {
+	if reason, ok := a.skipped[entry]; ok {
+		return nil, fmt.Errorf("%s %s: skipped archive entry (%s)", op, ArchivePath(archive, entry), reason)
+	}
+	f, ok := a.entries[entry]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: ArchivePath(archive, entry), Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
+
+// withArchive calls fn with the opened archive, which must not be used after
+// fn returns.
+func withArchive(path string, fn func(*openArchive) error) error {
+	for {
+		archive, created, evicted := findArchive(path)
+		if evicted != nil {
+			evicted.close()
+		}
+		if created {
+			archive.err = archive.load()
+			if archive.err != nil {
+				// Try again on the next read
+				archives.Lock()
+				if archives.open[path] == archive {
+					delete(archives.open, path)
+				}
+				archives.Unlock()
+			}
+			archive.Unlock()
+		}
+		archive.RLock()
+		if archive.closed {
+			// Evicted before it could be read
+			archive.RUnlock()
+			continue
+		}
+		defer archive.RUnlock()
+		if archive.err != nil {
+			return archive.err
+		}
+		return fn(archive)
+	}
+}
+
+// findArchive returns the open archive at path, or a new archive that is
+// locked until the caller loads it. If too many archives are open, the least
+// recently used one is removed and returned, for the caller to close.
+func findArchive(path string) (*openArchive, bool, *openArchive) {
+	archives.Lock()
+	defer archives.Unlock()
+	archives.clock++
+	if archive, ok := archives.open[path]; ok {
+		archive.lastUsed = archives.clock
+		return archive, false, nil
+	}
+	var evicted *openArchive
+	if len(archives.open) >= maxOpenArchives {
+		for _, archive := range archives.open {
+			if evicted == nil || archive.lastUsed < evicted.lastUsed {
+				evicted = archive
+			}
+		}
+		delete(archives.open, evicted.path)
+	}
+	archive := &openArchive{path: path, lastUsed: archives.clock}
+	archive.Lock()
+	archives.open[path] = archive
+	return archive, true, evicted
+}
+
+// CloseArchives closes the archives that were opened to read entries.
+func CloseArchives() {
+	archives.Lock()
+	open := make([]*openArchive, 0, len(archives.open))
+	for path, archive := range archives.open {
+		open = append(open, archive)
+		delete(archives.open, path)
+	}
+	archives.Unlock()
+	for _, archive := range open {
+		archive.close()
+	}
+}
+
+// statEntry returns the header of an entry in an archive.
+func statEntry(archive string, entry string) (*zip.FileHeader, error) {
+	var header *zip.FileHeader
+	err := withArchive(archive, func(a *openArchive) error {
+		f, err := a.lookup("stat", archive, entry)
+		if err != nil {
+			return err
+		}
+		header = &f.FileHeader
+		return nil
+	})
+	return header, err
+}
+
+// ListArchive returns the virtual paths of the files in an archive that are
+// safe to read.
+func ListArchive(archive string) ([]string, error) {
+	var acc []string
+	err := withArchive(archive, func(a *openArchive) error {
+		for _, f := range a.files {
+			acc = append(acc, ArchivePath(archive, f.Name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive %s: %w", archive, err)
+	}
+	return acc, nil
+}
+
+// StatFile is os.Stat for files on disk and entries in archives.
+func StatFile(path string) (fs.FileInfo, error) {
+	archive, entry, ok := SplitArchivePath(path)
+	if !ok {
+		return os.Stat(path)
+	}
+	header, err := statEntry(archive, entry)
+	if err != nil {
+		return nil, err
+	}
+	return header.FileInfo(), nil
+}
+
+// ReadFile is os.ReadFile for files on disk and entries in archives.
+func ReadFile(path string) ([]byte, error) {
+	archive, entry, ok := SplitArchivePath(path)
+	if !ok {
+		return os.ReadFile(path)
+	}
+	var data []byte
+	err := withArchive(archive, func(a *openArchive) error {
+		f, err := a.lookup("open", archive, entry)
+		if err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		data, err = io.ReadAll(rc)
+		return err
+	})
+	return data, err
+}
+
+// MaterializeFile returns a path on disk with the contents of the file, for
+// programs that can't read entries in archives (e.g. an editor). Entries are
+// extracted on demand to a read-only copy in the output directory.
+func MaterializeFile(path string) (string, error) {
+	archive, entry, ok := SplitArchivePath(path)
+	if !ok {
+		return path, nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(entry)) {
+		return "", fmt.Errorf("invalid archive entry: %s", path)
+	}
+	data, err := ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Each archive gets its own directory, so entries keep their relative paths
+	outputDir, err := EnsureOutputDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(archive))
+	dest := filepath.Join(outputDir, "archives", hex.EncodeToString(sum[:8]), filepath.FromSlash(entry))
+	err = os.MkdirAll(filepath.Dir(dest), 0o755)
+	if err != nil {
+		return "", err
+	}
+	err = os.Remove(dest)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	err = os.WriteFile(dest, data, 0o444)
+	if err != nil {
+		return "", err
+	}
+	return dest, nil
+}
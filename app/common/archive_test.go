@@ -0,0 +1,175 @@
+package common
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, files map[string]string) string {
+	path := filepath.Join(t.TempDir(), "test-sources.jar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, body := range files {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestArchiveFiles(t *testing.T) {
+	archive := writeTestArchive(t, map[string]string{
+		"com/example/Foo.java": "package com.example;\n",
+		"com/example/Bar.java": "package com.example;\n\nclass Bar {}\n",
+	})
+	defer CloseArchives()
+	// Entries are listed as virtual paths
+	paths, err := ListArchive(archive)
+	if err != nil {
+		t.Fatalf("ListArchive failed: %v", err)
+	}
+	slices.Sort(paths)
+	expected := []string{archive + "!/com/example/Bar.java", archive + "!/com/example/Foo.java"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("unexpected paths: got %v, expected %v", paths, expected)
+	}
+	// Entries are read in place
+	data, err := ReadFile(paths[0])
+	if err != nil || string(data) != "package com.example;\n\nclass Bar {}\n" {
+		t.Errorf("unexpected contents: %q (%v)", data, err)
+	}
+	info, err := StatFile(paths[0])
+	if err != nil || info.Size() != int64(len(data)) {
+		t.Errorf("unexpected size: %v (%v)", info, err)
+	}
+	if !Exists(paths[1]) || Exists(archive+"!/com/example/Missing.java") {
+		t.Errorf("Exists does not match the archive entries")
+	}
+	// Files on disk are unaffected
+	archivePath, entry, ok := SplitArchivePath(archive)
+	if ok || archivePath != archive || entry != "" {
+		t.Errorf("SplitArchivePath split a file on disk: %s, %s", archivePath, entry)
+	}
+	if !Exists(archive) {
+		t.Errorf("Exists does not find the archive")
+	}
+}
+
+func TestArchiveSkipsUnsafeEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evil-sources.jar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, name := range []string{"com/example/Ok.java", "../../.bashrc", "/etc/passwd", `..\evil.java`} {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte("x"))
+	}
+	link := &zip.FileHeader{Name: "com/example/Link.java"}
+	link.SetMode(os.ModeSymlink | 0o777)
+	entry, err := w.CreateHeader(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Write([]byte("/etc/passwd"))
+	w.Close()
+	f.Close()
+	defer CloseArchives()
+	// Only the safe entry is listed or readable
+	paths, err := ListArchive(path)
+	if err != nil {
+		t.Fatalf("ListArchive failed: %v", err)
+	}
+	if !slices.Equal(paths, []string{path + "!/com/example/Ok.java"}) {
+		t.Errorf("unexpected paths: %v", paths)
+	}
+	if _, err := ReadFile(path + "!/../../.bashrc"); err == nil {
+		t.Errorf("ReadFile read an unsafe entry")
+	}
+	// The skipped entries are reported
+	reasons := make(map[string]string)
+	for _, skipped := range SkippedArchiveEntries() {
+		if skipped.Archive == path {
+			reasons[skipped.Entry] = skipped.Reason
+		}
+	}
+	expected := map[string]string{
+		"../../.bashrc":         "unsafe path",
+		"/etc/passwd":           "unsafe path",
+		`..\evil.java`:          "unsafe path",
+		"com/example/Link.java": "symlink",
+	}
+	if len(reasons) != len(expected) {
+		t.Errorf("unexpected skipped entries: %v", reasons)
+	}
+	for entry, reason := range expected {
+		if reasons[entry] != reason {
+			t.Errorf("entry %q: got reason %q, expected %q", entry, reasons[entry], reason)
+		}
+	}
+}
+
+func TestCheckEntryBudgets(t *testing.T) {
+	entry := &zip.File{FileHeader: zip.FileHeader{Name: "a/B.java", UncompressedSize64: 1 << 10}}
+	entry.SetMode(0o644)
+	if reason := checkEntry(entry, 0, 0); reason != "" {
+		t.Errorf("entry was skipped: %s", reason)
+	}
+	if reason := checkEntry(entry, maxArchiveEntries, 0); reason != "too many entries" {
+		t.Errorf("unexpected reason: %q", reason)
+	}
+	if reason := checkEntry(entry, 0, maxArchiveSize); reason != "archive too large" {
+		t.Errorf("unexpected reason: %q", reason)
+	}
+	entry.UncompressedSize64 = maxArchiveEntrySize + 1
+	if reason := checkEntry(entry, 0, 0); reason != "entry too large" {
+		t.Errorf("unexpected reason: %q", reason)
+	}
+}
+
+func TestArchivesReadInParallel(t *testing.T) {
+	defer CloseArchives()
+	// More archives than stay open, so some are evicted while they are read
+	paths := make([]string, 0)
+	for range maxOpenArchives * 2 {
+		archive := writeTestArchive(t, map[string]string{"a/A.java": "class A {}\n", "a/B.java": "class B {}\n"})
+		paths = append(paths, archive+"!/a/A.java", archive+"!/a/B.java")
+	}
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range paths {
+				path := paths[(i+worker*5)%len(paths)]
+				data, err := ReadFile(path)
+				if err != nil || len(data) != len("class A {}\n") {
+					t.Errorf("unexpected contents of %s: %q (%v)", path, data, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	archives.Lock()
+	defer archives.Unlock()
+	if len(archives.open) > maxOpenArchives {
+		t.Errorf("%d archives are open, expected at most %d", len(archives.open), maxOpenArchives)
+	}
+}
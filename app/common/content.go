@@ -0,0 +1,142 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Files larger than this are not added to the content index (e.g. minified bundles)
+const maxContentSize = 1 << 20
+
+var ErrContentSearchDisabled = errors.New(
+	"content search is not available, rebuild rtfm with -tags sqlite_fts5")
+
+type MatchLine struct {
+	Number int
+	Text   string
+}
+
+type ContentMatch struct {
+	Language Language
+	Path     string
+	Score    float64
+	Lines    []MatchLine
+}
+
+func readContent(path string) (string, error) {
+	info, err := StatFile(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxContentSize {
+		return "", fmt.Errorf("file too large: %d bytes", info.Size())
+	}
+	data, err := ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// MakeContentQuery turns each term into an FTS5 phrase, so punctuation in
+// error messages is matched literally instead of parsed as query syntax.
+func MakeContentQuery(terms []string) string {
+	phrases := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(phrases, " AND ")
+}
+
+func matchLines(body string, terms []string, limit int) []MatchLine {
+	lowerTerms := make([]string, len(terms))
+	for i, term := range terms {
+		lowerTerms[i] = strings.ToLower(strings.TrimSpace(term))
+	}
+	acc := make([]MatchLine, 0)
+	for i, line := range strings.Split(body, "\n") {
+		lowerLine := strings.ToLower(line)
+		for _, term := range lowerTerms {
+			if term != "" && strings.Contains(lowerLine, term) {
+				acc = append(acc, MatchLine{Number: i + 1, Text: line})
+				break
+			}
+		}
+		if len(acc) >= limit {
+			break
+		}
+	}
+	return acc
+}
+
+func FindContent(db *sql.DB, language Language, terms []string, limit int, maxLines int) ([]*ContentMatch, error) {
+	if !contentSearchEnabled {
+		return nil, ErrContentSearchDisabled
+	}
+	query := MakeContentQuery(terms)
+	if query == "" {
+		return nil, fmt.Errorf("no search terms")
+	}
+	// Prepare the statement
+	stmt, err := db.Prepare(`
+		SELECT files.language, files.path, bm25(content) AS score
+		FROM content
+		JOIN files ON files.id = content.rowid
+		WHERE content MATCH ?
+		  AND (? = '' OR files.language = ?)
+		ORDER BY score
+		LIMIT ?
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+	// Execute the statement
+	rows, err := stmt.Query(query, language, language, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	// Map the results to ContentMatch
+	var matches []*ContentMatch
+	for rows.Next() {
+		var match ContentMatch
+		err := rows.Scan(&match.Language, &match.Path, &match.Score)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		matches = append(matches, &match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	// Find the matching lines in each file
+	for _, match := range matches {
+		body, err := readContent(match.Path)
+		if err != nil {
+			continue
+		}
+		match.Lines = matchLines(body, terms, maxLines)
+	}
+	return matches, nil
+}
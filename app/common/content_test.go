@@ -0,0 +1,22 @@
+package common
+
+import "testing"
+
+func TestMakeContentQuery(t *testing.T) {
+	query := MakeContentQuery([]string{"computeIfAbsent", ` say "hi" `, ""})
+	expected := `"computeIfAbsent" AND "say ""hi"""`
+	if query != expected {
+		t.Errorf("MakeContentQuery returned %q, expected %q", query, expected)
+	}
+}
+
+func TestMatchLines(t *testing.T) {
+	body := "package foo\n\nthrow new IllegalStateException(\"Queue full\");\nqueue full again\n"
+	lines := matchLines(body, []string{"queue FULL"}, 1)
+	if len(lines) != 1 {
+		t.Fatalf("matchLines returned unexpected number of lines: %d", len(lines))
+	}
+	if lines[0].Number != 3 {
+		t.Errorf("matchLines returned unexpected line number: %d", lines[0].Number)
+	}
+}
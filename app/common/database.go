@@ -15,13 +15,19 @@
 package common
 
 import (
+	"cmp"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 func getOutputDir() string {
@@ -51,6 +57,47 @@ func EnsureOutputDir() (string, error) {
 	return outputDir, nil
 }
 
+// driverName is the sqlite3 driver with the functions rtfm's queries use
+const driverName = "sqlite3_rtfm"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", matchRegexp, true)
+		},
+	})
+}
+
+// Maximum number of compiled patterns cached by the REGEXP function
+const maxCachedRegexps = 16
+
+// regexps caches compiled patterns, as the REGEXP function is called for
+// every row
+var regexps = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// matchRegexp implements `value REGEXP pattern` in SQLite.
+func matchRegexp(pattern string, value string) (bool, error) {
+	regexps.Lock()
+	re, ok := regexps.patterns[pattern]
+	if !ok {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			regexps.Unlock()
+			return false, err
+		}
+		if len(regexps.patterns) >= maxCachedRegexps {
+			clear(regexps.patterns)
+		}
+		regexps.patterns[pattern] = re
+	}
+	regexps.Unlock()
+	return re.MatchString(value), nil
+}
+
 func OpenDB() (*sql.DB, error) {
 	// Create the application data directory if it doesn't exist
 	dir, err := EnsureOutputDir()
@@ -59,37 +106,141 @@ func OpenDB() (*sql.DB, error) {
 	}
 	// Create the database file if it doesn't exist
 	path := filepath.Join(dir, "rtfm.db")
-	db, err := sql.Open("sqlite3", path)
+	// Indexing reads fingerprints while a single writer commits documents, so
+	// readers use the write-ahead log and writers wait for each other
+	db, err := sql.Open(driverName, path+"?_journal_mode=WAL&_busy_timeout=10000&_txlock=immediate")
 	if err != nil {
 		return nil, err
 	}
-	// Create tables if they don't exist
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS code (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			language INTEGER,
-			name TEXT,
-			path TEXT,
-			UNIQUE(language, name, path) ON CONFLICT IGNORE
-		)
-	`)
+	// Drop tables created by older versions of rtfm
+	err = migrateDB(db)
 	if err != nil {
 		return nil, err
 	}
+	// Create tables if they don't exist
+	for _, statement := range schema {
+		_, err = db.Exec(statement)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// The content table is contentless: matching lines are read back from disk
+	if contentSearchEnabled {
+		_, err = db.Exec(`
+			CREATE VIRTUAL TABLE IF NOT EXISTS content USING fts5(
+				body,
+				content = '',
+				contentless_delete = 1
+			)
+		`)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return db, nil
 }
 
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS code (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		language TEXT,
+		kind INTEGER,
+		name TEXT,
+		path TEXT,
+		line INTEGER,
+		package TEXT,
+		version TEXT,
+		root TEXT,
+		UNIQUE(language, name, path, line) ON CONFLICT IGNORE
+	)`,
+	`CREATE INDEX IF NOT EXISTS code_path ON code (path)`,
+	// Fingerprints of indexed files, used to skip unchanged files
+	`CREATE TABLE IF NOT EXISTS files (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		language TEXT,
+		path TEXT UNIQUE,
+		root TEXT,
+		mtime INTEGER,
+		size INTEGER,
+		hash TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS files_root ON files (root)`,
+	// Fingerprints of source roots (virtual environments, node_modules, jars, ...)
+	`CREATE TABLE IF NOT EXISTS roots (
+		path TEXT PRIMARY KEY,
+		language TEXT,
+		mtime INTEGER,
+		size INTEGER
+	)`,
+	// When documents were last selected, to rank them higher. Unlike the other
+	// tables, this isn't rebuilt by rtfm index, so it is kept across schema
+	// changes.
+	`CREATE TABLE IF NOT EXISTS history (
+		language TEXT,
+		name TEXT,
+		used INTEGER,
+		PRIMARY KEY (language, name)
+	)`,
+}
+
+// schemaVersion is bumped whenever the tables change. The index is a cache of
+// what's on disk, so older tables are dropped and rebuilt by the next index run.
+const schemaVersion = 7
+
+func migrateDB(db *sql.DB) error {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version == schemaVersion {
+		return nil
+	}
+	slog.Info("Index schema changed, run rtfm index to rebuild it", "from", version, "to", schemaVersion)
+	tables := []string{"code", "files", "roots"}
+	if contentSearchEnabled {
+		tables = append(tables, "content")
+	}
+	for _, table := range tables {
+		_, err = db.Exec("DROP TABLE IF EXISTS " + table)
+		if err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", table, err)
+		}
+	}
+	_, err = db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
+	if err != nil {
+		return fmt.Errorf("failed to write schema version: %w", err)
+	}
+	return nil
+}
+
+// IndexDocuments replaces the documents of every file referenced by the given
+// documents, so all documents for a file must be indexed in the same call.
 func IndexDocuments(db *sql.DB, documents []*SearchDocument) error {
-	// Create a transaction
 	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
+	err = writeDocuments(tx, documents)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func writeDocuments(tx *sql.Tx, documents []*SearchDocument) error {
+	// Remove documents from a previous index of the same files
+	for _, doc := range uniqueFiles(documents) {
+		_, err := tx.Exec("DELETE FROM code WHERE path = ?", doc.Path)
+		if err != nil {
+			return fmt.Errorf("failed to delete documents: %w", err)
+		}
+	}
 	// Prepare the statement
 	stmt, err := tx.Prepare(`
-		INSERT OR IGNORE INTO code (language, name, path)
-		VALUES (?, ?, ?)
+		INSERT OR IGNORE INTO code (language, kind, name, path, line, package, version, root)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -97,44 +248,290 @@ func IndexDocuments(db *sql.DB, documents []*SearchDocument) error {
 	defer stmt.Close()
 	// Insert the documents
 	for _, doc := range documents {
-		_, err := stmt.Exec(doc.Language, doc.Name, doc.Path)
+		_, err := stmt.Exec(doc.Language, doc.Kind, doc.Name, doc.Path, doc.Line, doc.Package, doc.Version, doc.Root)
 		if err != nil {
 			return fmt.Errorf("failed to insert document: %w", err)
 		}
 	}
-	return tx.Commit()
+	// Record the fingerprint and contents of each file
+	return indexFiles(tx, uniqueFiles(documents))
 }
 
-func FindDocuments(db *sql.DB, language Language, query string, exact bool) ([]*SearchDocument, error) {
-	// Prepare the statement
-	stmt, err := db.Prepare(`
-		SELECT language, name, path
-		FROM code
-		WHERE (? = -1 OR language = ?)
-		  AND name LIKE ?
+// clearDocuments removes the documents of files that were parsed without any
+// (e.g. because they no longer parse), and records their fingerprints.
+func clearDocuments(tx *sql.Tx, files []*SearchDocument) error {
+	for _, file := range files {
+		_, err := tx.Exec("DELETE FROM code WHERE path = ?", file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to delete documents: %w", err)
+		}
+	}
+	return indexFiles(tx, files)
+}
+
+// uniqueFiles returns the first document for each path.
+func uniqueFiles(documents []*SearchDocument) []*SearchDocument {
+	seen := make(map[string]struct{})
+	acc := make([]*SearchDocument, 0)
+	for _, doc := range documents {
+		if _, ok := seen[doc.Path]; !ok {
+			seen[doc.Path] = struct{}{}
+			acc = append(acc, doc)
+		}
+	}
+	return acc
+}
+
+func indexFiles(tx *sql.Tx, files []*SearchDocument) error {
+	// Prepare the statements
+	fileStmt, err := tx.Prepare(`
+		INSERT INTO files (language, path, root, mtime, size, hash)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET
+			language = excluded.language,
+			root = excluded.root,
+			mtime = excluded.mtime,
+			size = excluded.size,
+			hash = excluded.hash
+		RETURNING id
 	`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
-	defer stmt.Close()
-	// Execute the statement
-	if !exact {
-		query = MakeFuzzy(query)
+	defer fileStmt.Close()
+	for _, doc := range files {
+		// Fingerprint the file
+		fingerprint, data, err := readFingerprint(doc.Path)
+		if err != nil {
+			slog.Warn("Error reading file", "path", doc.Path, "error", err)
+			continue
+		}
+		var id int64
+		err = fileStmt.QueryRow(
+			doc.Language, doc.Path, doc.Root,
+			fingerprint.ModTime, fingerprint.Size, fingerprint.Hash,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to insert file: %w", err)
+		}
+		// Replace the file's contents in the full-text index
+		if !contentSearchEnabled {
+			continue
+		}
+		_, err = tx.Exec("DELETE FROM content WHERE rowid = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete content: %w", err)
+		}
+		if len(data) > maxContentSize {
+			continue
+		}
+		_, err = tx.Exec("INSERT INTO content (rowid, body) VALUES (?, ?)", id, string(data))
+		if err != nil {
+			return fmt.Errorf("failed to insert content: %w", err)
+		}
 	}
-	rows, err := stmt.Query(language, language, query)
+	return nil
+}
+
+// DocumentFilter limits the documents FindDocuments returns. Empty fields
+// match every document.
+type DocumentFilter struct {
+	Language Language
+	// Package matches package names that contain it (e.g. guava matches
+	// com.google.guava:guava)
+	Package string
+	// Version matches the version, or versions that start with it followed by
+	// a dot (e.g. 4 matches 4.17.21)
+	Version string
+}
+
+// Match is how FindDocuments matches names with a query.
+type Match int
+
+const (
+	// MatchQuery matches the query language of ParseQuery
+	MatchQuery Match = iota
+	// MatchLike matches a SQL LIKE pattern (e.g. %Session.request)
+	MatchLike
+	// MatchRegexp matches a regular expression, in the syntax of Go's regexp
+	// package (e.g. ^org\.springframework\..*Factory$)
+	MatchRegexp
+)
+
+// FindDocuments returns the documents whose names match a query, ranked by
+// relevance.
+func FindDocuments(db *sql.DB, filter DocumentFilter, query string, match Match) ([]*SearchDocument, error) {
+	// Parse the query into SQL conditions, and the terms to rank by
+	conditions := []string{"name LIKE ?"}
+	args := []any{query}
+	terms := query
+	var parsed *Query
+	switch match {
+	case MatchQuery:
+		var err error
+		parsed, err = ParseQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		conditions, args = parsed.conditions()
+		terms = strings.Join(parsed.Terms, " ")
+		// Qualifiers narrow down the query when the filter doesn't
+		filter.Language = cmp.Or(filter.Language, parsed.Filter.Language)
+		filter.Package = cmp.Or(filter.Package, parsed.Filter.Package)
+		filter.Version = cmp.Or(filter.Version, parsed.Filter.Version)
+	case MatchRegexp:
+		_, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		conditions = []string{"name REGEXP ?"}
+		terms = ""
+	}
+	// Execute the query
+	statement := `
+		SELECT id, language, kind, name, path, line, package, version, root
+		FROM code
+		WHERE (? = '' OR language = ?)
+		  AND (? = '' OR package LIKE '%' || ? || '%' ESCAPE '\')
+		  AND (? = '' OR version = ? OR version LIKE ? || '.%' ESCAPE '\')`
+	for _, condition := range conditions {
+		statement += "\n\t\t  AND " + condition
+	}
+	statement += "\n\t\tORDER BY name, package, version, path, line"
+	args = append([]any{
+		filter.Language, filter.Language,
+		filter.Package, escapeLike(filter.Package),
+		filter.Version, filter.Version, escapeLike(filter.Version),
+	}, args...)
+	rows, err := db.Query(statement, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
-	// Map the results to SearchDocument
+	// Map the results to SearchDocument, keeping those that match the parts
+	// of the query SQL can't check
 	var documents []*SearchDocument
 	for rows.Next() {
 		var doc SearchDocument
-		err := rows.Scan(&doc.Language, &doc.Name, &doc.Path)
+		err := scanDocument(rows, &doc)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-		documents = append(documents, &doc)
+		if parsed == nil || parsed.Matches(&doc) {
+			documents = append(documents, &doc)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
 	}
+	// Rank the results
+	history, err := loadHistory(db)
+	if err != nil {
+		return nil, err
+	}
+	rankDocuments(documents, terms, history, time.Now())
 	return documents, nil
 }
+
+func scanDocument(row interface{ Scan(...any) error }, doc *SearchDocument) error {
+	return row.Scan(&doc.ID, &doc.Language, &doc.Kind, &doc.Name, &doc.Path, &doc.Line, &doc.Package, &doc.Version, &doc.Root)
+}
+
+// FindDocument returns the document with an ID.
+func FindDocument(db *sql.DB, id int64) (*SearchDocument, error) {
+	var doc SearchDocument
+	row := db.QueryRow(`
+		SELECT id, language, kind, name, path, line, package, version, root
+		FROM code
+		WHERE id = ?
+	`, id)
+	err := scanDocument(row, &doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("document not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find document: %w", err)
+	}
+	return &doc, nil
+}
+
+// FindFileDocument returns the first document of an indexed file (usually its
+// module), to check that a path is in the index.
+func FindFileDocument(db *sql.DB, path string) (*SearchDocument, error) {
+	var doc SearchDocument
+	row := db.QueryRow(`
+		SELECT id, language, kind, name, path, line, package, version, root
+		FROM code
+		WHERE path = ?
+		ORDER BY line, id
+		LIMIT 1
+	`, path)
+	err := scanDocument(row, &doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("file not indexed: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find document: %w", err)
+	}
+	return &doc, nil
+}
+
+// PackageSummary is a library version in the index, with its number of
+// documents. Package is "" for files from unknown libraries.
+type PackageSummary struct {
+	Language  Language
+	Package   string
+	Version   string
+	Documents int
+}
+
+// ListPackages returns the libraries in the index, of a language (or every
+// language if "").
+func ListPackages(db *sql.DB, language Language) ([]PackageSummary, error) {
+	rows, err := db.Query(`
+		SELECT language, package, version, COUNT(*)
+		FROM code
+		WHERE (? = '' OR language = ?)
+		GROUP BY language, package, version
+		ORDER BY language, package, version
+	`, language, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	defer rows.Close()
+	var packages []PackageSummary
+	for rows.Next() {
+		var pkg PackageSummary
+		err := rows.Scan(&pkg.Language, &pkg.Package, &pkg.Version, &pkg.Documents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, rows.Err()
+}
+
+// FindPackageDocuments returns the documents of exactly one version of a
+// library, unranked.
+func FindPackageDocuments(db *sql.DB, language Language, pkg string, version string) ([]*SearchDocument, error) {
+	rows, err := db.Query(`
+		SELECT id, language, kind, name, path, line, package, version, root
+		FROM code
+		WHERE language = ? AND package = ? AND version = ?
+		ORDER BY path, line
+	`, language, pkg, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	var documents []*SearchDocument
+	for rows.Next() {
+		var doc SearchDocument
+		err := scanDocument(rows, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		documents = append(documents, &doc)
+	}
+	return documents, rows.Err()
+}
@@ -0,0 +1,168 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindDocumentsFilter(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db, err := OpenDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// The same module from two versions of a library, in different roots
+	dir := t.TempDir()
+	documents := make([]*SearchDocument, 0)
+	for _, version := range []string{"4.17.21", "3.10.1"} {
+		root := filepath.Join(dir, version, "node_modules")
+		path := filepath.Join(root, "lodash", "get.js")
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte("export function get() {}\n"), 0o644)
+		documents = append(documents, &SearchDocument{
+			Language: "javascript", Kind: Module, Name: "lodash/get.js", Path: path,
+			Package: "lodash", Version: version, Root: root,
+		})
+	}
+	err = IndexDocuments(db, documents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filter   DocumentFilter
+		expected []string
+	}{
+		{DocumentFilter{}, []string{"3.10.1", "4.17.21"}},
+		{DocumentFilter{Package: "lodash", Version: "4"}, []string{"4.17.21"}},
+		{DocumentFilter{Version: "3.10.1"}, []string{"3.10.1"}},
+		{DocumentFilter{Version: "3.1"}, nil},
+		{DocumentFilter{Package: "dash"}, []string{"3.10.1", "4.17.21"}},
+		// Package names and versions are matched literally
+		{DocumentFilter{Package: "lo_ash"}, nil},
+		{DocumentFilter{Package: "%"}, nil},
+		{DocumentFilter{Version: "3_10"}, nil},
+		{DocumentFilter{Language: "python"}, nil},
+	}
+	for _, test := range tests {
+		docs, err := FindDocuments(db, test.filter, "get", MatchQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var versions []string
+		for _, doc := range docs {
+			if doc.Root != filepath.Join(dir, doc.Version, "node_modules") {
+				t.Errorf("unexpected root for %s: %s", doc.Version, doc.Root)
+			}
+			versions = append(versions, doc.Version)
+		}
+		slices.Sort(versions)
+		if !slices.Equal(versions, test.expected) {
+			t.Errorf("%+v: expected %v, got %v", test.filter, test.expected, versions)
+		}
+	}
+	// Documents can be found again by their ID (e.g. by rtfm preview)
+	docs, err := FindDocuments(db, DocumentFilter{}, "get", MatchQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := FindDocument(db, docs[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *doc != *docs[1] {
+		t.Errorf("expected %+v, got %+v", docs[1], doc)
+	}
+	if _, err := FindDocument(db, -1); err == nil {
+		t.Error("expected an error for a missing document")
+	}
+}
+
+func TestFindDocumentsMatch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db, err := OpenDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	path := filepath.Join(t.TempDir(), "Beans.java")
+	os.WriteFile(path, []byte("class BeanFactory {}\n"), 0o644)
+	documents := make([]*SearchDocument, 0)
+	for i, name := range []string{
+		"org.springframework.beans.BeanFactory",
+		"org.springframework.beans.FactoryBean",
+		"org.springframework.beans.factory.BeanFactoryUtils",
+		"com.example.WidgetFactory",
+	} {
+		documents = append(documents, &SearchDocument{Language: "java", Kind: Class, Name: name, Path: path, Line: i + 1})
+	}
+	err = IndexDocuments(db, documents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query    string
+		match    Match
+		expected []string
+	}{
+		{`^org\.springframework\..*Factory$`, MatchRegexp, []string{"org.springframework.beans.BeanFactory"}},
+		{`Factory$`, MatchRegexp, []string{"com.example.WidgetFactory", "org.springframework.beans.BeanFactory"}},
+		{`springframework /factory$/ -/^org.*Utils/`, MatchQuery, []string{"org.springframework.beans.BeanFactory"}},
+		{`%.FactoryBean`, MatchLike, []string{"org.springframework.beans.FactoryBean"}},
+	}
+	for _, test := range tests {
+		docs, err := FindDocuments(db, DocumentFilter{}, test.query, test.match)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, doc := range docs {
+			names = append(names, doc.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.query, test.expected, names)
+		}
+	}
+	if _, err := FindDocuments(db, DocumentFilter{}, "(", MatchRegexp); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
+
+func TestFindPackageDocuments(t *testing.T) {
+	db := openTestDB(t)
+	documents := make([]*SearchDocument, 0)
+	for _, pkg := range []string{"lodash@4.17.21", "lodash@4.17.2", "lodash-es@4.17.21", "@"} {
+		name, version, _ := strings.Cut(pkg, "@")
+		path := filepath.Join(t.TempDir(), "get.js")
+		os.WriteFile(path, []byte("export function get() {}\n"), 0o644)
+		documents = append(documents, &SearchDocument{
+			Language: "javascript", Kind: Module, Name: "get.js", Path: path, Package: name, Version: version,
+		})
+	}
+	if err := IndexDocuments(db, documents); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pkg     string
+		version string
+		path    string
+	}{
+		{"lodash", "4.17.21", documents[0].Path},
+		{"lodash", "4.17.2", documents[1].Path},
+		// Documents without a package are a package of their own
+		{"", "", documents[3].Path},
+	}
+	for _, test := range tests {
+		docs, err := FindPackageDocuments(db, "javascript", test.pkg, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(docs) != 1 || docs[0].Path != test.path {
+			t.Errorf("%s@%s: unexpected documents %v", test.pkg, test.version, docs)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+type IndexOptions struct {
+	// Full re-parses every file, ignoring fingerprints from previous runs
+	Full bool
+	// Jobs is the number of roots extracted and files parsed in parallel
+	Jobs int
+	// Project limits the index to the libraries a project uses, if set
+	Project *Project
+}
+
+type Fingerprint struct {
+	ModTime int64
+	Size    int64
+	Hash    string
+}
+
+// statFingerprint fingerprints a file without reading it. Entries in archives
+// are hashed by their CRC-32, since reproducible builds give every entry the
+// same modification time.
+func statFingerprint(path string) (Fingerprint, error) {
+	if archive, entry, ok := SplitArchivePath(path); ok {
+		header, err := statEntry(archive, entry)
+		if err != nil {
+			return Fingerprint{}, err
+		}
+		return Fingerprint{
+			ModTime: header.Modified.UnixNano(),
+			Size:    int64(header.UncompressedSize64),
+			Hash:    fmt.Sprintf("crc32:%08x", header.CRC32),
+		}, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{ModTime: info.ModTime().UnixNano(), Size: info.Size()}, nil
+}
+
+func readFingerprint(path string) (Fingerprint, []byte, error) {
+	fingerprint, err := statFingerprint(path)
+	if err != nil {
+		return fingerprint, nil, err
+	}
+	data, err := ReadFile(path)
+	if err != nil {
+		return fingerprint, nil, err
+	}
+	if fingerprint.Hash == "" {
+		sum := sha256.Sum256(data)
+		fingerprint.Hash = hex.EncodeToString(sum[:])
+	}
+	return fingerprint, data, nil
+}
+
+// RootChanged reports whether a source root (a virtual environment,
+// node_modules directory, Go module, jar, ...) was modified since it was last
+// indexed. Directories are compared by modification time, which changes when
+// packages are installed or removed.
+func RootChanged(db *sql.DB, root string, opts IndexOptions) (bool, error) {
+	if opts.Full {
+		return true, nil
+	}
+	current, err := statFingerprint(root)
+	if err != nil {
+		return false, err
+	}
+	var previous Fingerprint
+	err = db.QueryRow("SELECT mtime, size FROM roots WHERE path = ?", root).
+		Scan(&previous.ModTime, &previous.Size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read root: %w", err)
+	}
+	return current.ModTime != previous.ModTime || current.Size != previous.Size, nil
+}
+
+// MarkRootIndexed records the fingerprint of a source root once all of its
+// files have been indexed.
+func MarkRootIndexed(db *sql.DB, language Language, root string) error {
+	fingerprint, err := statFingerprint(root)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO roots (path, language, mtime, size)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET
+			language = excluded.language,
+			mtime = excluded.mtime,
+			size = excluded.size
+	`, root, language, fingerprint.ModTime, fingerprint.Size)
+	if err != nil {
+		return fmt.Errorf("failed to update root: %w", err)
+	}
+	return nil
+}
+
+// RemoveStaleRoots removes the files of every indexed root of the language
+// that is not in roots (e.g. a deleted virtual environment), and returns the
+// removed roots.
+func RemoveStaleRoots(db *sql.DB, language Language, roots []string) ([]string, error) {
+	current := make(map[string]struct{}, len(roots))
+	for _, root := range roots {
+		current[root] = struct{}{}
+	}
+	// Find the stale roots
+	indexed, err := queryStrings(db, "SELECT path FROM roots WHERE language = ?", language)
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]string, 0)
+	for _, root := range indexed {
+		if _, ok := current[root]; !ok {
+			stale = append(stale, root)
+		}
+	}
+	// Remove their files
+	for _, root := range stale {
+		slog.Info("Removing stale root", "root", root)
+		paths, err := queryStrings(db, "SELECT path FROM files WHERE root = ?", root)
+		if err != nil {
+			return nil, err
+		}
+		err = removeFiles(db, paths)
+		if err != nil {
+			return nil, err
+		}
+		_, err = db.Exec("DELETE FROM roots WHERE path = ?", root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete root: %w", err)
+		}
+	}
+	return stale, nil
+}
+
+// FileChanged reports whether a file needs to be parsed again. Files whose
+// modification time changed but whose contents did not are only touched, and
+// their new fingerprint is returned so the writer can record it.
+func FileChanged(db *sql.DB, path string, opts IndexOptions) (bool, *Fingerprint, error) {
+	if opts.Full {
+		return true, nil, nil
+	}
+	current, err := statFingerprint(path)
+	if err != nil {
+		return false, nil, err
+	}
+	var previous Fingerprint
+	err = db.QueryRow("SELECT mtime, size, hash FROM files WHERE path = ?", path).
+		Scan(&previous.ModTime, &previous.Size, &previous.Hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if current.ModTime == previous.ModTime && current.Size == previous.Size &&
+		(current.Hash == "" || current.Hash == previous.Hash) {
+		return false, nil, nil
+	}
+	// Compare the contents
+	current, _, err = readFingerprint(path)
+	if err != nil {
+		return false, nil, err
+	}
+	if current.Hash != previous.Hash {
+		return true, nil, nil
+	}
+	return false, &current, nil
+}
+
+// touchFiles records the new fingerprints of files whose contents didn't
+// change.
+func touchFiles(tx *sql.Tx, touched map[string]Fingerprint) error {
+	for path, fingerprint := range touched {
+		_, err := tx.Exec("UPDATE files SET mtime = ?, size = ? WHERE path = ?",
+			fingerprint.ModTime, fingerprint.Size, path)
+		if err != nil {
+			return fmt.Errorf("failed to update file: %w", err)
+		}
+	}
+	return nil
+}
+
+// RemoveMissingFiles removes the documents of indexed files that no longer
+// exist, and returns how many files were removed.
+func RemoveMissingFiles(db *sql.DB, language Language) (int, error) {
+	// Sorted, so entries of the same archive are checked together
+	paths, err := queryStrings(db, "SELECT path FROM files WHERE language = ? ORDER BY path", language)
+	if err != nil {
+		return 0, err
+	}
+	missing := make([]string, 0)
+	for _, path := range paths {
+		if !Exists(path) {
+			missing = append(missing, path)
+		}
+	}
+	err = removeFiles(db, missing)
+	if err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+func removeFiles(db *sql.DB, paths []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, path := range paths {
+		_, err = tx.Exec("DELETE FROM code WHERE path = ?", path)
+		if err != nil {
+			return fmt.Errorf("failed to delete documents: %w", err)
+		}
+		if contentSearchEnabled {
+			_, err = tx.Exec("DELETE FROM content WHERE rowid IN (SELECT id FROM files WHERE path = ?)", path)
+			if err != nil {
+				return fmt.Errorf("failed to delete content: %w", err)
+			}
+		}
+		_, err = tx.Exec("DELETE FROM files WHERE path = ?", path)
+		if err != nil {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func queryStrings(db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	acc := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		acc = append(acc, value)
+	}
+	return acc, rows.Err()
+}
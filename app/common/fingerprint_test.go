@@ -0,0 +1,163 @@
+package common
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Setenv("HOME", t.TempDir())
+	db, err := OpenDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// indexTestFile writes a file in the root and indexes a document for it.
+func indexTestFile(t *testing.T, db *sql.DB, root string, name string, body string) string {
+	path := filepath.Join(root, name)
+	os.MkdirAll(root, 0o755)
+	os.WriteFile(path, []byte(body), 0o644)
+	err := IndexDocuments(db, []*SearchDocument{{Language: "test", Kind: Module, Name: name, Path: path, Root: root}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileChanged(t *testing.T) {
+	db := openTestDB(t)
+	root := t.TempDir()
+	later := time.Now().Add(time.Minute)
+	tests := []struct {
+		name     string
+		change   func(path string)
+		changed  bool
+		touched  bool
+		previous bool
+	}{
+		{"unchanged", func(path string) {}, false, false, true},
+		{"touched", func(path string) { os.Chtimes(path, later, later) }, false, true, true},
+		{"edited", func(path string) {
+			os.WriteFile(path, []byte("edited\n"), 0o644)
+			os.Chtimes(path, later, later)
+		}, true, false, true},
+		{"same size", func(path string) {
+			os.WriteFile(path, []byte("abcdefg\n"), 0o644)
+			os.Chtimes(path, later, later)
+		}, true, false, true},
+		{"new", func(path string) {}, true, false, false},
+	}
+	for _, test := range tests {
+		path := filepath.Join(root, test.name+".txt")
+		if test.previous {
+			indexTestFile(t, db, root, test.name+".txt", "content\n")
+		} else {
+			os.WriteFile(path, []byte("content\n"), 0o644)
+		}
+		test.change(path)
+		changed, touched, err := FileChanged(db, path, IndexOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if changed != test.changed || (touched != nil) != test.touched {
+			t.Errorf("%s: expected changed %v and touched %v, got %v and %v",
+				test.name, test.changed, test.touched, changed, touched)
+		}
+		if touched != nil && touched.ModTime != later.UnixNano() {
+			t.Errorf("%s: unexpected fingerprint %+v", test.name, touched)
+		}
+		// Full re-parses everything
+		if changed, _, _ := FileChanged(db, path, IndexOptions{Full: true}); !changed {
+			t.Errorf("%s: not changed with Full", test.name)
+		}
+	}
+	// FileChanged doesn't write, the touched fingerprint is recorded by the writer
+	path := filepath.Join(root, "touched.txt")
+	if changed, touched, _ := FileChanged(db, path, IndexOptions{}); changed || touched == nil {
+		t.Errorf("touched file was recorded: %v, %v", changed, touched)
+	}
+}
+
+func TestRootChanged(t *testing.T) {
+	db := openTestDB(t)
+	root := t.TempDir()
+	changed, err := RootChanged(db, root, IndexOptions{})
+	if err != nil || !changed {
+		t.Errorf("new root: expected changed, got %v (%v)", changed, err)
+	}
+	if err := MarkRootIndexed(db, "test", root); err != nil {
+		t.Fatal(err)
+	}
+	changed, err = RootChanged(db, root, IndexOptions{})
+	if err != nil || changed {
+		t.Errorf("indexed root: expected unchanged, got %v (%v)", changed, err)
+	}
+	if changed, _ := RootChanged(db, root, IndexOptions{Full: true}); !changed {
+		t.Errorf("indexed root: not changed with Full")
+	}
+	// Installing a package changes the directory
+	os.Mkdir(filepath.Join(root, "package"), 0o755)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(root, later, later)
+	changed, err = RootChanged(db, root, IndexOptions{})
+	if err != nil || !changed {
+		t.Errorf("modified root: expected changed, got %v (%v)", changed, err)
+	}
+}
+
+func TestRemoveStaleRoots(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+	kept, deleted := filepath.Join(dir, "kept"), filepath.Join(dir, "deleted")
+	for _, root := range []string{kept, deleted} {
+		indexTestFile(t, db, root, "a.txt", "a\n")
+		if err := MarkRootIndexed(db, "test", root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.RemoveAll(deleted)
+	stale, err := RemoveStaleRoots(db, "test", []string{kept})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stale, []string{deleted}) {
+		t.Errorf("unexpected stale roots: %v", stale)
+	}
+	roots, _ := queryStrings(db, "SELECT path FROM roots")
+	files, _ := queryStrings(db, "SELECT root FROM files")
+	code, _ := queryStrings(db, "SELECT root FROM code")
+	for _, paths := range [][]string{roots, files, code} {
+		if !slices.Equal(paths, []string{kept}) {
+			t.Errorf("unexpected roots after removing stale roots: %v", paths)
+		}
+	}
+	// Roots of other languages are kept
+	stale, err = RemoveStaleRoots(db, "other", nil)
+	if err != nil || len(stale) != 0 {
+		t.Errorf("unexpected stale roots of another language: %v (%v)", stale, err)
+	}
+}
+
+func TestRemoveMissingFiles(t *testing.T) {
+	db := openTestDB(t)
+	root := t.TempDir()
+	kept := indexTestFile(t, db, root, "kept.txt", "a\n")
+	vanished := indexTestFile(t, db, root, "vanished.txt", "b\n")
+	os.Remove(vanished)
+	count, err := RemoveMissingFiles(db, "test")
+	if err != nil || count != 1 {
+		t.Errorf("expected 1 missing file, got %d (%v)", count, err)
+	}
+	files, _ := queryStrings(db, "SELECT path FROM files")
+	code, _ := queryStrings(db, "SELECT path FROM code")
+	if !slices.Equal(files, []string{kept}) || !slices.Equal(code, []string{kept}) {
+		t.Errorf("unexpected files after removing missing files: %v, %v", files, code)
+	}
+}
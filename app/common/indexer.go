@@ -0,0 +1,160 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Language is the name of an indexed language (e.g. "java"), which is also
+// stored in the index and used by --lang.
+type Language string
+
+// Root is a directory or archive of source files that is indexed as a unit
+// (a virtual environment, node_modules directory, Go module, jar, ...).
+type Root struct {
+	Path string
+	// Package is set for roots that contain a single library (e.g. a jar or Go
+	// module), and Version is the version of the library or toolchain
+	Package string
+	Version string
+	// Packages are the libraries in a root of several libraries (e.g. the
+	// distributions in a virtual environment), if the indexer knows them
+	Packages []string
+	// Source is what the indexer found the root from (e.g. Maven coordinates)
+	Source any
+}
+
+// Indexer finds and parses the libraries of one language. Each language
+// registers its indexer with Register.
+type Indexer interface {
+	Language() Language
+	// Roots discovers the source roots on the system (and in the project, if
+	// the index is scoped to one)
+	Roots(opts IndexOptions) ([]*Root, error)
+	// Files lists the source files of a root, extracting or generating them
+	// first if needed
+	Files(root *Root) ([]string, error)
+	// Parse returns the documents of a file: the file itself and its symbols.
+	// Files of a root are parsed concurrently, after Files returns.
+	Parse(root *Root, path string, code []byte) ([]*SearchDocument, error)
+	// CommentPrefix starts a line comment in the language
+	CommentPrefix() string
+	// Lexer is the name of the chroma lexer for the language
+	Lexer() string
+}
+
+// DependencyFinder is implemented by indexers that can read the library
+// versions a project uses from its lockfiles.
+type DependencyFinder interface {
+	Dependencies(projectDir string) ([]Dependency, error)
+}
+
+// RootRemover is implemented by indexers that keep files for their roots
+// (e.g. generated stubs), to remove them once the roots no longer exist.
+// Current are the roots found by this run.
+type RootRemover interface {
+	RemoveRoots(stale []string, current []*Root) error
+}
+
+var indexers = make(map[Language]Indexer)
+
+func Register(indexer Indexer) {
+	if _, ok := indexers[indexer.Language()]; ok {
+		panic(fmt.Sprintf("indexer already registered for %s", indexer.Language()))
+	}
+	indexers[indexer.Language()] = indexer
+}
+
+// Indexers returns the registered indexers, ordered by language.
+func Indexers() []Indexer {
+	acc := make([]Indexer, 0, len(indexers))
+	for _, indexer := range indexers {
+		acc = append(acc, indexer)
+	}
+	slices.SortFunc(acc, func(a, b Indexer) int { return strings.Compare(string(a.Language()), string(b.Language())) })
+	return acc
+}
+
+func LookupIndexer(language Language) (Indexer, bool) {
+	indexer, ok := indexers[language]
+	return indexer, ok
+}
+
+// LanguageFromName returns the registered language with the name, or "" (which
+// matches every language) if there is none.
+func LanguageFromName(name string) Language {
+	language := Language(strings.ToLower(name))
+	if _, ok := indexers[language]; !ok {
+		return ""
+	}
+	return language
+}
+
+// Index indexes the roots of a language that changed since the last run, and
+// removes the roots and files that no longer exist.
+func Index(indexer Indexer, opts IndexOptions) error {
+	language := indexer.Language()
+	slog.Info("Indexing", "language", language)
+	// Connect to the database
+	db, err := OpenDB()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+	// Find the source roots
+	roots, err := indexer.Roots(opts)
+	if err != nil {
+		return fmt.Errorf("error finding %s roots: %w", language, err)
+	}
+	if opts.Project != nil {
+		roots = slices.DeleteFunc(roots, func(root *Root) bool { return !opts.Project.IncludesRoot(language, root) })
+	}
+	// Index the changed roots
+	stats, err := runPipeline(db, indexer, roots, opts)
+	if err != nil {
+		return err
+	}
+	slog.Info("Indexed documents", "language", language, "roots", len(roots),
+		"changed", stats.Roots, "files", stats.Files, "documents", stats.Documents)
+	// Remove roots that no longer exist (roots outside of the project were
+	// not discovered, but still exist)
+	if opts.Project == nil {
+		paths := make([]string, 0, len(roots))
+		for _, root := range roots {
+			paths = append(paths, root.Path)
+		}
+		stale, err := RemoveStaleRoots(db, language, paths)
+		if err != nil {
+			return fmt.Errorf("error removing stale roots: %w", err)
+		}
+		if remover, ok := indexer.(RootRemover); ok {
+			err = remover.RemoveRoots(stale, roots)
+			if err != nil {
+				return fmt.Errorf("error removing stale roots: %w", err)
+			}
+		}
+	}
+	// Remove files that no longer exist
+	removed, err := RemoveMissingFiles(db, language)
+	if err != nil {
+		return fmt.Errorf("error removing missing files: %w", err)
+	}
+	slog.Info("Removed missing files", "language", language, "count", removed)
+	return nil
+}
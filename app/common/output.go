@@ -0,0 +1,93 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Formats of non-interactive search results
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+)
+
+// documentOutput is the JSON representation of a search document.
+type documentOutput struct {
+	ID       int64    `json:"id"`
+	Language Language `json:"language"`
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Package  string   `json:"package"`
+	Version  string   `json:"version"`
+	Root     string   `json:"root"`
+}
+
+func newDocumentOutput(doc *SearchDocument) documentOutput {
+	return documentOutput{
+		ID:       doc.ID,
+		Language: doc.Language,
+		Kind:     NameFromKind(doc.Kind),
+		Name:     doc.Name,
+		Path:     doc.Path,
+		Line:     doc.Line,
+		Package:  doc.Package,
+		Version:  doc.Version,
+		Root:     doc.Root,
+	}
+}
+
+// WriteDocuments prints search documents, one per line for text (tab
+// separated, with the location as path:line) and jsonl, or as a JSON array.
+func WriteDocuments(w io.Writer, docs []*SearchDocument, format string) error {
+	switch format {
+	case FormatText:
+		for _, doc := range docs {
+			location := doc.Path
+			if doc.Line > 0 {
+				location = fmt.Sprintf("%s:%d", doc.Path, doc.Line)
+			}
+			_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				doc.Language, NameFromKind(doc.Kind), doc.Name, packageLabel(doc), location)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		acc := make([]documentOutput, 0, len(docs))
+		for _, doc := range docs {
+			acc = append(acc, newDocumentOutput(doc))
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(acc)
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		for _, doc := range docs {
+			err := encoder.Encode(newDocumentOutput(doc))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format: %s (expected %s, %s or %s)", format, FormatText, FormatJSON, FormatJSONL)
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteDocuments(t *testing.T) {
+	docs := []*SearchDocument{
+		{ID: 1, Language: "python", Kind: Module, Name: "requests.sessions", Path: "/venv/requests/sessions.py",
+			Package: "requests", Version: "2.31.0"},
+		{ID: 2, Language: "python", Kind: Method, Name: "requests.sessions.Session.request", Path: "/venv/requests/sessions.py",
+			Line: 500, Package: "requests", Version: "2.31.0"},
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{FormatText, "python\tmodule\trequests.sessions\trequests@2.31.0\t/venv/requests/sessions.py\n" +
+			"python\tmethod\trequests.sessions.Session.request\trequests@2.31.0\t/venv/requests/sessions.py:500\n"},
+		{FormatJSONL, `{"id":1,"language":"python","kind":"module","name":"requests.sessions","path":"/venv/requests/sessions.py",` +
+			`"line":0,"package":"requests","version":"2.31.0","root":""}` + "\n" +
+			`{"id":2,"language":"python","kind":"method","name":"requests.sessions.Session.request","path":"/venv/requests/sessions.py",` +
+			`"line":500,"package":"requests","version":"2.31.0","root":""}` + "\n"},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := WriteDocuments(&b, docs, test.format); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.format, test.expected, b.String())
+		}
+	}
+	if err := WriteDocuments(&bytes.Buffer{}, docs, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
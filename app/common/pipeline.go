@@ -0,0 +1,267 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"database/sql"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Documents are written in transactions of about this many documents
+const writeBatchSize = 10_000
+
+// IndexStats counts what changed in an index run.
+type IndexStats struct {
+	Roots     int
+	Files     int
+	Documents int
+}
+
+// rootState tracks the files of a root through the pipeline, so the root is
+// only marked as indexed once all of them were written.
+type rootState struct {
+	root *Root
+	// files is the number of results expected by the writer (one per file,
+	// plus one once the root's files are listed)
+	files   int
+	changed bool
+	// shared is set for roots with libraries the project doesn't use, which
+	// are never marked as indexed, so a run without the project indexes them
+	shared bool
+	// done and failed are only used by the writer
+	done   int
+	failed bool
+}
+
+type fileTask struct {
+	state *rootState
+	path  string
+}
+
+// fileResult is the outcome of one step for a root. Parsed is set for files
+// that changed (even if they have no documents), touched is set for files
+// whose contents didn't change, and an error fails the whole root.
+type fileResult struct {
+	state     *rootState
+	path      string
+	parsed    bool
+	documents []*SearchDocument
+	touched   *Fingerprint
+	err       error
+}
+
+// runPipeline indexes the roots that changed in four stages: the roots are
+// discovered by the caller, extract workers list (and extract or generate)
+// their files, parse workers parse the changed files, and a single writer
+// writes the documents in batches and marks the roots as indexed.
+func runPipeline(db *sql.DB, indexer Indexer, roots []*Root, opts IndexOptions) (IndexStats, error) {
+	jobs := max(opts.Jobs, 1)
+	rootQueue := make(chan *Root)
+	taskQueue := make(chan fileTask, jobs*4)
+	results := make(chan fileResult, jobs*4)
+	// Extract: find the files of each changed root
+	var extractors sync.WaitGroup
+	for range jobs {
+		extractors.Add(1)
+		go func() {
+			defer extractors.Done()
+			for root := range rootQueue {
+				extractRoot(db, indexer, root, opts, taskQueue, results)
+			}
+		}()
+	}
+	// Parse: create documents for each changed file
+	var parsers sync.WaitGroup
+	for range jobs {
+		parsers.Add(1)
+		go func() {
+			defer parsers.Done()
+			for task := range taskQueue {
+				results <- parseFile(db, indexer, task, opts)
+			}
+		}()
+	}
+	go func() {
+		for _, root := range roots {
+			rootQueue <- root
+		}
+		close(rootQueue)
+		extractors.Wait()
+		close(taskQueue)
+		parsers.Wait()
+		close(results)
+	}()
+	// Write: batch the documents and mark finished roots as indexed
+	return writeResults(db, indexer.Language(), results)
+}
+
+func extractRoot(
+	db *sql.DB,
+	indexer Indexer,
+	root *Root,
+	opts IndexOptions,
+	taskQueue chan<- fileTask,
+	results chan<- fileResult,
+) {
+	state := &rootState{root: root, files: 1}
+	state.shared = opts.Project != nil && opts.Project.sharesRoot(root)
+	// Skip roots where nothing was installed or removed
+	changed, err := RootChanged(db, root.Path, opts)
+	if err != nil || !changed {
+		results <- fileResult{state: state, err: err}
+		return
+	}
+	state.changed = true
+	paths, err := indexer.Files(root)
+	if err != nil {
+		results <- fileResult{state: state, err: err}
+		return
+	}
+	// The state is complete before any task is sent, so the writer sees it
+	paths = Dedupe(paths)
+	state.files += len(paths)
+	for _, path := range paths {
+		taskQueue <- fileTask{state: state, path: path}
+	}
+	results <- fileResult{state: state}
+}
+
+func parseFile(db *sql.DB, indexer Indexer, task fileTask, opts IndexOptions) fileResult {
+	result := fileResult{state: task.state, path: task.path}
+	changed, touched, err := FileChanged(db, task.path, opts)
+	if err != nil || !changed {
+		result.touched, result.err = touched, err
+		return result
+	}
+	code, err := ReadFile(task.path)
+	if err != nil {
+		slog.Warn("Error reading file", "path", task.path, "error", err)
+		return result
+	}
+	// Files that no longer parse lose their previous documents
+	result.parsed = true
+	documents, err := indexer.Parse(task.state.root, task.path, code)
+	if err != nil {
+		slog.Warn("Error parsing file", "path", task.path, "error", err)
+		return result
+	}
+	// Files of libraries the project doesn't use are left for a run without it
+	if task.state.shared {
+		documents = slices.DeleteFunc(documents, func(doc *SearchDocument) bool { return !opts.Project.Includes(doc) })
+		if len(documents) == 0 {
+			result.parsed = false
+			return result
+		}
+	}
+	result.documents = documents
+	return result
+}
+
+func writeResults(db *sql.DB, language Language, results <-chan fileResult) (IndexStats, error) {
+	var stats IndexStats
+	var writeErr error
+	batch := make([]*SearchDocument, 0, writeBatchSize)
+	// empty are the parsed files without documents
+	empty := make([]*SearchDocument, 0)
+	touched := make(map[string]Fingerprint)
+	pending := func() int { return len(batch) + len(empty) + len(touched) }
+	// finished roots wait for the batch they were written in
+	finished := make([]*rootState, 0)
+	flush := func() {
+		if writeErr == nil && pending() > 0 {
+			writeErr = writeBatch(db, batch, empty, touched)
+		}
+		batch = batch[:0]
+		empty = empty[:0]
+		clear(touched)
+		for _, state := range finished {
+			if writeErr != nil {
+				break
+			}
+			if err := MarkRootIndexed(db, language, state.root.Path); err != nil {
+				writeErr = err
+			}
+		}
+		finished = finished[:0]
+	}
+	// Keep receiving after an error, so the workers can finish
+	for result := range results {
+		state := result.state
+		if result.err != nil {
+			slog.Error("Error indexing root", "root", state.root.Path, "error", result.err)
+			state.failed = true
+		}
+		if result.parsed {
+			stats.Files++
+			stats.Documents += len(result.documents)
+			batch = append(batch, result.documents...)
+			if len(result.documents) == 0 {
+				empty = append(empty, &SearchDocument{Language: language, Path: result.path, Root: state.root.Path})
+			}
+		}
+		if result.touched != nil {
+			touched[result.path] = *result.touched
+		}
+		state.done++
+		if state.done < state.files {
+			if pending() >= writeBatchSize {
+				flush()
+			}
+			continue
+		}
+		// All of the root's files were parsed (a failed root is retried on
+		// the next run, unchanged roots don't need to be marked)
+		if state.changed && !state.failed && !state.shared {
+			stats.Roots++
+			finished = append(finished, state)
+		}
+		if pending() >= writeBatchSize || len(finished) > 0 {
+			flush()
+		}
+	}
+	flush()
+	return stats, writeErr
+}
+
+// writeBatch writes the documents of parsed files, clears the documents of
+// parsed files that have none, and records the fingerprints of touched files
+// in one transaction.
+func writeBatch(
+	db *sql.DB,
+	documents []*SearchDocument,
+	empty []*SearchDocument,
+	touched map[string]Fingerprint,
+) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = writeDocuments(tx, documents)
+	if err != nil {
+		return err
+	}
+	err = clearDocuments(tx, empty)
+	if err != nil {
+		return err
+	}
+	err = touchFiles(tx, touched)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
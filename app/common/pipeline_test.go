@@ -0,0 +1,158 @@
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIndexer indexes directories of .txt files, with a symbol per line
+// (empty files have no documents).
+type testIndexer struct{}
+
+func (testIndexer) Language() Language { return "test" }
+
+func (testIndexer) Roots(opts IndexOptions) ([]*Root, error) { return nil, nil }
+
+func (testIndexer) Files(root *Root) ([]string, error) {
+	if strings.HasSuffix(root.Path, "broken") {
+		return nil, fmt.Errorf("broken root")
+	}
+	return filepath.Glob(filepath.Join(root.Path, "*.txt"))
+}
+
+func (testIndexer) Parse(root *Root, path string, code []byte) ([]*SearchDocument, error) {
+	if len(code) == 0 {
+		return nil, nil
+	}
+	// Each file is a library
+	pkg := strings.TrimSuffix(filepath.Base(path), ".txt")
+	documents := []*SearchDocument{{Language: "test", Kind: Module, Name: path, Path: path, Package: pkg, Root: root.Path}}
+	for i, line := range strings.Split(strings.TrimSpace(string(code)), "\n") {
+		documents = append(documents, &SearchDocument{
+			Language: "test", Kind: Constant, Name: line, Path: path, Line: i + 1, Package: pkg, Root: root.Path,
+		})
+	}
+	return documents, nil
+}
+
+func (testIndexer) CommentPrefix() string { return "#" }
+
+func (testIndexer) Lexer() string { return "text" }
+
+func countRows(t *testing.T, db *sql.DB, query string) int {
+	var count int
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestRunPipeline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db, err := OpenDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Create roots of files with two symbols each
+	dir := t.TempDir()
+	roots := make([]*Root, 0)
+	for i := range 20 {
+		rootDir := filepath.Join(dir, fmt.Sprintf("root%d", i))
+		os.Mkdir(rootDir, 0o755)
+		for j := range 30 {
+			body := fmt.Sprintf("A%d_%d\nB%d_%d\n", i, j, i, j)
+			os.WriteFile(filepath.Join(rootDir, fmt.Sprintf("file%d.txt", j)), []byte(body), 0o644)
+		}
+		roots = append(roots, &Root{Path: rootDir})
+	}
+	brokenDir := filepath.Join(dir, "broken")
+	os.Mkdir(brokenDir, 0o755)
+	roots = append(roots, &Root{Path: brokenDir})
+	// Every changed root is indexed, except for the one that fails
+	opts := IndexOptions{Jobs: 4}
+	stats, err := runPipeline(db, testIndexer{}, roots, opts)
+	if err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	if stats.Roots != 20 || stats.Files != 600 || stats.Documents != 1800 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM code"); count != 1800 {
+		t.Errorf("unexpected number of documents: %d", count)
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM roots"); count != 20 {
+		t.Errorf("unexpected number of indexed roots: %d", count)
+	}
+	// Nothing is parsed again, but the failed root is retried
+	stats, err = runPipeline(db, testIndexer{}, roots, opts)
+	if err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	if stats.Roots != 0 || stats.Files != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	// A file that no longer has documents loses its previous ones
+	path := filepath.Join(dir, "root0", "file0.txt")
+	os.WriteFile(path, nil, 0o644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "root0"), future, future)
+	stats, err = runPipeline(db, testIndexer{}, roots, opts)
+	if err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	if stats.Roots != 1 || stats.Files != 1 || stats.Documents != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM code WHERE path = '"+path+"'"); count != 0 {
+		t.Errorf("unexpected number of documents: %d", count)
+	}
+	if changed, _, err := FileChanged(db, path, opts); err != nil || changed {
+		t.Errorf("file was not recorded as indexed: %v (%v)", changed, err)
+	}
+}
+
+func TestRunPipelineSharedRoot(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db, err := OpenDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// A root of several libraries outside of the project, which uses one
+	rootDir := t.TempDir()
+	for _, name := range []string{"used", "unused"} {
+		os.WriteFile(filepath.Join(rootDir, name+".txt"), []byte("A\n"), 0o644)
+	}
+	root := &Root{Path: rootDir, Packages: []string{"used", "unused"}}
+	project := &Project{Dir: t.TempDir(), versions: make(map[Language]map[string]map[string]struct{})}
+	project.add(Dependency{Language: "test", Package: "used"})
+	if !project.IncludesRoot("test", root) {
+		t.Fatal("root with a used library was not included")
+	}
+	// Only the used library is indexed, and the root isn't marked as indexed
+	stats, err := runPipeline(db, testIndexer{}, []*Root{root}, IndexOptions{Jobs: 2, Project: project})
+	if err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	if stats.Roots != 0 || stats.Files != 1 || stats.Documents != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	packages, _ := queryStrings(db, "SELECT DISTINCT package FROM code")
+	if len(packages) != 1 || packages[0] != "used" {
+		t.Errorf("unexpected packages: %v", packages)
+	}
+	// A run without the project indexes the rest of the root
+	stats, err = runPipeline(db, testIndexer{}, []*Root{root}, IndexOptions{Jobs: 2})
+	if err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	if stats.Roots != 1 || stats.Files != 1 || stats.Documents != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
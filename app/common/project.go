@@ -0,0 +1,147 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// StandardLibrary is the package of a language's standard library, which every
+// project uses.
+const StandardLibrary = "stdlib"
+
+// Dependency is a library version that a project uses. Version is "" if the
+// project doesn't pin one.
+type Dependency struct {
+	Language Language
+	Package  string
+	Version  string
+}
+
+// Project is a directory with lockfiles (go.sum, package-lock.json, ...) that
+// list the libraries it uses.
+type Project struct {
+	Dir          string
+	Dependencies []Dependency
+	// versions are the versions of each package ("" for any version)
+	versions map[Language]map[string]map[string]struct{}
+}
+
+// LoadProject reads the dependencies of a project from the lockfiles each
+// language understands.
+func LoadProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("project is not a directory: %s", dir)
+	}
+	project := &Project{Dir: dir, versions: make(map[Language]map[string]map[string]struct{})}
+	for _, indexer := range Indexers() {
+		finder, ok := indexer.(DependencyFinder)
+		if !ok {
+			continue
+		}
+		dependencies, err := finder.Dependencies(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s dependencies: %w", indexer.Language(), err)
+		}
+		for _, dependency := range dependencies {
+			project.add(dependency)
+		}
+	}
+	if len(project.Dependencies) == 0 {
+		return nil, fmt.Errorf("no dependencies found in the lockfiles of %s", dir)
+	}
+	return project, nil
+}
+
+func (p *Project) add(dependency Dependency) {
+	p.Dependencies = append(p.Dependencies, dependency)
+	packages, ok := p.versions[dependency.Language]
+	if !ok {
+		packages = make(map[string]map[string]struct{})
+		p.versions[dependency.Language] = packages
+	}
+	versions, ok := packages[dependency.Package]
+	if !ok {
+		versions = make(map[string]struct{})
+		packages[dependency.Package] = versions
+	}
+	versions[dependency.Version] = struct{}{}
+}
+
+// Uses reports whether the project uses a version of a package.
+func (p *Project) Uses(language Language, pkg string, version string) bool {
+	if pkg == StandardLibrary {
+		return true
+	}
+	versions, ok := p.versions[language][pkg]
+	if !ok {
+		return false
+	}
+	if _, ok := versions[""]; ok {
+		return true
+	}
+	_, ok = versions[version]
+	return ok
+}
+
+func (p *Project) contains(path string) bool {
+	return strings.HasPrefix(path, p.Dir+string(filepath.Separator))
+}
+
+// IncludesRoot reports whether a root is part of the project: roots of a single
+// library if the project uses it, and roots of several libraries (a virtual
+// environment or node_modules directory) if they are in the project directory,
+// or have a library the project uses (e.g. a Poetry virtual environment in
+// ~/.cache/pypoetry). Only the documents the project uses are indexed from
+// the latter.
+func (p *Project) IncludesRoot(language Language, root *Root) bool {
+	if root.Package != "" {
+		return p.Uses(language, root.Package, root.Version)
+	}
+	if p.contains(root.Path) {
+		return true
+	}
+	return slices.ContainsFunc(root.Packages, func(pkg string) bool {
+		_, ok := p.versions[language][pkg]
+		return ok
+	})
+}
+
+// sharesRoot reports whether a root has libraries the project doesn't use,
+// which are filtered out by document.
+func (p *Project) sharesRoot(root *Root) bool {
+	return root.Package == "" && !p.contains(root.Path)
+}
+
+// Includes reports whether a document is from a library the project uses (or
+// from an unknown library in the project directory).
+func (p *Project) Includes(doc *SearchDocument) bool {
+	if doc.Package == "" {
+		return p.contains(doc.Path)
+	}
+	return p.Uses(doc.Language, doc.Package, doc.Version)
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIncludesRoot(t *testing.T) {
+	dir := t.TempDir()
+	project := &Project{Dir: filepath.Join(dir, "project"), versions: make(map[Language]map[string]map[string]struct{})}
+	project.add(Dependency{Language: "python", Package: "requests", Version: "2.31.0"})
+	project.add(Dependency{Language: "go", Package: "github.com/spf13/cobra", Version: "v1.9.1"})
+	tests := []struct {
+		name     string
+		language Language
+		root     *Root
+		expected bool
+	}{
+		{"used library", "go", &Root{Path: "/go/cobra", Package: "github.com/spf13/cobra", Version: "v1.9.1"}, true},
+		{"other version", "go", &Root{Path: "/go/cobra", Package: "github.com/spf13/cobra", Version: "v1.8.0"}, false},
+		{"standard library", "go", &Root{Path: "/go/src", Package: StandardLibrary}, true},
+		{"venv in the project", "python", &Root{Path: filepath.Join(dir, "project", ".venv")}, true},
+		{"venv with a used library", "python", &Root{Path: "/venv", Packages: []string{"idna", "requests"}}, true},
+		{"venv without a used library", "python", &Root{Path: "/venv", Packages: []string{"idna"}}, false},
+		{"other language", "javascript", &Root{Path: "/node_modules", Packages: []string{"requests"}}, false},
+	}
+	for _, test := range tests {
+		if actual := project.IncludesRoot(test.language, test.root); actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search query, e.g. `cfgLdr lang:java kind:class -test`.
+// Terms match names that contain them, or whose words they abbreviate
+// (cfgLdr matches ConfigLoader, CHM matches ConcurrentHashMap). Every term,
+// qualifier and negation must match.
+type Query struct {
+	// Terms are the words of the query
+	Terms []string
+	// Excluded are the terms that names must not contain (-test)
+	Excluded []string
+	// Patterns are regular expressions that names must match (/Map$/)
+	Patterns []*regexp.Regexp
+	// ExcludedPatterns are regular expressions that names must not match
+	ExcludedPatterns []*regexp.Regexp
+	// Filter has the lang:, pkg: and version: qualifiers
+	Filter DocumentFilter
+	// Kinds are the kinds of the kind: qualifiers (any of them matches)
+	Kinds []Kind
+}
+
+// splitQuery splits a query into words, keeping spaces in /regex/ terms.
+func splitQuery(text string) ([]string, error) {
+	words := make([]string, 0)
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return words, nil
+		}
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if start := strings.TrimPrefix(text, "-"); strings.HasPrefix(start, "/") {
+			// Regular expressions end at the next unescaped slash
+			offset := len(text) - len(start) + 1
+			end = -1
+			for i := offset; i < len(text); i++ {
+				if text[i] == '\\' {
+					i++
+				} else if text[i] == '/' {
+					end = i + 1
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated regular expression: %s", text)
+			}
+		}
+		if end < 0 {
+			end = len(text)
+		}
+		words = append(words, text[:end])
+		text = text[end:]
+	}
+}
+
+// ParseQuery parses the query language of rtfm search.
+func ParseQuery(text string) (*Query, error) {
+	words, err := splitQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	query := &Query{}
+	for _, word := range words {
+		negated := len(word) > 1 && word[0] == '-'
+		if negated {
+			word = word[1:]
+		}
+		// Regular expressions
+		if len(word) > 1 && word[0] == '/' && word[len(word)-1] == '/' {
+			pattern, err := regexp.Compile("(?i)" + word[1:len(word)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %s: %w", word, err)
+			}
+			if negated {
+				query.ExcludedPatterns = append(query.ExcludedPatterns, pattern)
+			} else {
+				query.Patterns = append(query.Patterns, pattern)
+			}
+			continue
+		}
+		// Qualifiers (other colons are part of names, e.g. lodash/get.js:get)
+		if name, value, found := strings.Cut(word, ":"); found && isQualifier(name) {
+			if negated {
+				return nil, fmt.Errorf("qualifiers can't be negated: -%s", word)
+			}
+			err := query.qualify(strings.ToLower(name), value)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if negated {
+			query.Excluded = append(query.Excluded, word)
+		} else {
+			query.Terms = append(query.Terms, word)
+		}
+	}
+	return query, nil
+}
+
+func isQualifier(name string) bool {
+	switch strings.ToLower(name) {
+	case "lang", "language", "pkg", "package", "version", "kind":
+		return true
+	}
+	return false
+}
+
+// qualify applies a qualifier to the query.
+func (q *Query) qualify(name string, value string) error {
+	if value == "" {
+		return fmt.Errorf("missing value of %s:", name)
+	}
+	switch name {
+	case "lang", "language":
+		q.Filter.Language = LanguageFromName(value)
+		if q.Filter.Language == "" {
+			return fmt.Errorf("unknown language: %s", value)
+		}
+	case "pkg", "package":
+		q.Filter.Package = value
+	case "version":
+		q.Filter.Version = value
+	case "kind":
+		for _, kindName := range strings.Split(value, ",") {
+			kind := KindFromName(kindName)
+			if kind < 0 {
+				return fmt.Errorf("unknown kind: %s", kindName)
+			}
+			q.Kinds = append(q.Kinds, kind)
+		}
+	}
+	return nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern (with \ as the escape
+// character).
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// subsequencePattern returns a LIKE pattern of names that contain the
+// characters of a term in order, which includes every name it can match.
+func subsequencePattern(term string) string {
+	var b strings.Builder
+	b.WriteString("%")
+	for _, r := range term {
+		b.WriteString(escapeLike(string(r)) + "%")
+	}
+	return b.String()
+}
+
+// conditions returns the SQL conditions on names that narrow down the
+// documents the query matches, before Matches filters them.
+func (q *Query) conditions() ([]string, []any) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	for _, term := range q.Terms {
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, subsequencePattern(term))
+	}
+	for _, term := range q.Excluded {
+		conditions = append(conditions, `name NOT LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	for _, pattern := range q.Patterns {
+		conditions = append(conditions, "name REGEXP ?")
+		args = append(args, pattern.String())
+	}
+	for _, pattern := range q.ExcludedPatterns {
+		conditions = append(conditions, "name NOT REGEXP ?")
+		args = append(args, pattern.String())
+	}
+	return conditions, args
+}
+
+// nameWords splits a name into lowercase words, at separators and camel case
+// humps (e.g. java, util, concurrent, hash and map).
+func nameWords(name string) []string {
+	words := make([]string, 0)
+	runes := []rune(name)
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		boundary := i == len(runes) || !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i])
+		if !boundary && i > start && unicode.IsUpper(runes[i]) {
+			// A capital starts a word after a lowercase letter, or ends an
+			// acronym (the S in HTTPServer)
+			boundary = !unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))
+			if boundary {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+			continue
+		}
+		if boundary {
+			if i > start {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+			}
+			start = i + 1
+		}
+	}
+	return words
+}
+
+// matchesWords reports whether a term abbreviates words of a name: it is split
+// into pieces that each start a later word and are in it in order (e.g. cfg and
+// ldr for config and loader).
+func matchesWords(words []string, term []rune) bool {
+	if len(term) == 0 {
+		return true
+	}
+	for i, word := range words {
+		runes := []rune(word)
+		if runes[0] != term[0] {
+			continue
+		}
+		// Find the longest piece of the term in the word, then try shorter
+		// pieces if the rest doesn't match
+		length := 1
+		for j := 1; j < len(runes) && length < len(term); j++ {
+			if runes[j] == term[length] {
+				length++
+			}
+		}
+		for ; length > 0; length-- {
+			if matchesWords(words[i+1:], term[length:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesTerm reports whether a name contains a term, or the term abbreviates
+// its words.
+func matchesTerm(name string, term string) bool {
+	lowerTerm := strings.ToLower(term)
+	if strings.Contains(strings.ToLower(name), lowerTerm) {
+		return true
+	}
+	if strings.ContainsAny(term, nameSeparators) {
+		return false
+	}
+	return matchesWords(nameWords(name), []rune(lowerTerm))
+}
+
+// Matches reports whether a document matches every part of the query.
+func (q *Query) Matches(doc *SearchDocument) bool {
+	if q.Filter.Language != "" && doc.Language != q.Filter.Language {
+		return false
+	}
+	if q.Filter.Package != "" && !strings.Contains(strings.ToLower(doc.Package), strings.ToLower(q.Filter.Package)) {
+		return false
+	}
+	if q.Filter.Version != "" && doc.Version != q.Filter.Version && !strings.HasPrefix(doc.Version, q.Filter.Version+".") {
+		return false
+	}
+	if len(q.Kinds) > 0 && !slices.Contains(q.Kinds, doc.Kind) {
+		return false
+	}
+	for _, term := range q.Terms {
+		if !matchesTerm(doc.Name, term) {
+			return false
+		}
+	}
+	for _, term := range q.Excluded {
+		if strings.Contains(strings.ToLower(doc.Name), strings.ToLower(term)) {
+			return false
+		}
+	}
+	for _, pattern := range q.Patterns {
+		if !pattern.MatchString(doc.Name) {
+			return false
+		}
+	}
+	for _, pattern := range q.ExcludedPatterns {
+		if pattern.MatchString(doc.Name) {
+			return false
+		}
+	}
+	return true
+}
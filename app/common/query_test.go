@@ -0,0 +1,57 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	query, err := ParseQuery(`cfgLdr pkg:guava version:33 kind:class,interface -Test /Load(er|ing)  x/ lodash/get.js:get`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(query.Terms, []string{"cfgLdr", "lodash/get.js:get"}) ||
+		!slices.Equal(query.Excluded, []string{"Test"}) ||
+		!slices.Equal(query.Kinds, []Kind{Class, Interface}) ||
+		query.Filter != (DocumentFilter{Package: "guava", Version: "33"}) ||
+		len(query.Patterns) != 1 || query.Patterns[0].String() != "(?i)Load(er|ing)  x" {
+		t.Errorf("unexpected query: %+v", query)
+	}
+	for _, text := range []string{"lang:cobol", "kind:widget", "pkg:", "-kind:method", "/unterminated", "/(/"} {
+		if _, err := ParseQuery(text); err == nil {
+			t.Errorf("expected an error parsing %q", text)
+		}
+	}
+}
+
+func TestQueryMatches(t *testing.T) {
+	tests := []struct {
+		query    string
+		name     string
+		kind     Kind
+		expected bool
+	}{
+		{"cfgLdr", "com.example.ConfigLoader", Class, true},
+		{"CHM", "java.util.concurrent.ConcurrentHashMap", Class, true},
+		{"hm", "java.util.concurrent.ConcurrentHashMap", Class, true},
+		{"HSrv", "org.example.HTTPServer", Class, true},
+		{"get user", "app.get_user_by_id", Function, true},
+		{"cfgLdr", "com.example.ConfigReader", Class, false},
+		{"mhc", "java.util.concurrent.ConcurrentHashMap", Class, false},
+		{"session.req", "requests.sessions.Session.request", Method, true},
+		{"Loader -test", "com.example.ConfigLoaderTest", Class, false},
+		{"Loader kind:method", "com.example.ConfigLoader", Class, false},
+		{"/loader$/", "com.example.ConfigLoader", Class, true},
+		{"-/^com\\./", "com.example.ConfigLoader", Class, false},
+	}
+	for _, test := range tests {
+		query, err := ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := &SearchDocument{Name: test.name, Kind: test.kind}
+		if query.Matches(doc) != test.expected {
+			t.Errorf("%q matches %s: expected %v", test.query, test.name, test.expected)
+		}
+	}
+}
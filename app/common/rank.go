@@ -0,0 +1,196 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Scores of the ways a document can match a query. Documents are ranked by the
+// sum of their scores.
+const (
+	// The simple name (e.g. HashMap in java.util.HashMap) is the query
+	scoreExactName = 100
+	// ... with the same case
+	scoreExactCase = 10
+	// The name ends with a qualified query (e.g. Session.request)
+	scoreQualifiedName = 50
+	// The simple name starts with the query
+	scorePrefix = 60
+	// The query is the initials of the simple name's humps (e.g. HM for HashMap)
+	scoreHumps = 40
+	// The simple name contains the query
+	scoreContains = 20
+	// Documents from standard libraries
+	scoreStandardLibrary = 15
+	// Types rather than their members
+	scoreType = 5
+	// A document that was just selected. The score halves every week.
+	scoreRecentUse = 80
+	// Documents lose a point per directory they are nested in, up to this
+	maxDepthPenalty = 10
+)
+
+// nameSeparators separate the parts of qualified names in every language
+const nameSeparators = "./:$#"
+
+// simpleName returns the unqualified name of a document (e.g. request for
+// requests.sessions.Session.request, or get for lodash/get.js).
+func simpleName(doc *SearchDocument) string {
+	if doc.Kind == Module && strings.Contains(doc.Name, "/") {
+		name := path.Base(doc.Name)
+		return strings.TrimSuffix(name, path.Ext(name))
+	}
+	return doc.Name[strings.LastIndexAny(doc.Name, nameSeparators)+1:]
+}
+
+// humps returns the lowercase initials of the words of a camel case or snake
+// case name (e.g. hm for HashMap, hs for HTTPServer and gu for get_user).
+func humps(name string) string {
+	var b strings.Builder
+	for _, word := range nameWords(name) {
+		r, _ := utf8.DecodeRuneInString(word)
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// depth returns the number of directories a document's file is nested in,
+// below its root.
+func depth(doc *SearchDocument) int {
+	p := doc.Path
+	if _, entry, ok := SplitArchivePath(p); ok {
+		p = entry
+	} else if doc.Root != "" && strings.HasPrefix(p, doc.Root+"/") {
+		p = strings.TrimPrefix(p, doc.Root+"/")
+	}
+	return strings.Count(strings.Trim(p, "/"), "/")
+}
+
+func isType(kind Kind) bool {
+	return kind == Class || kind == Interface || kind == Enum || kind == Record || kind == Type
+}
+
+// historyKey identifies a selected document across versions and re-indexing.
+type historyKey struct {
+	language Language
+	name     string
+}
+
+// scoreDocument scores how well a document matches a query (its last term, if
+// it has several).
+func scoreDocument(doc *SearchDocument, query string, history map[historyKey]time.Time, now time.Time) int {
+	score := 0
+	terms := strings.Fields(strings.ReplaceAll(query, "%", " "))
+	if len(terms) > 0 {
+		term := terms[len(terms)-1]
+		lowerTerm := strings.ToLower(term)
+		simpleTerm := lowerTerm[strings.LastIndexAny(lowerTerm, nameSeparators)+1:]
+		name := simpleName(doc)
+		lowerName := strings.ToLower(name)
+		switch {
+		case simpleTerm == "":
+		case lowerName == simpleTerm:
+			score += scoreExactName
+			if strings.HasSuffix(term, name) {
+				score += scoreExactCase
+			}
+		case strings.HasPrefix(lowerName, simpleTerm):
+			score += scorePrefix
+		case len(simpleTerm) > 1 && strings.HasPrefix(humps(name), simpleTerm):
+			score += scoreHumps
+		case strings.Contains(lowerName, simpleTerm):
+			score += scoreContains
+		}
+		// Qualified queries should match the end of the name
+		lowerDocName := strings.ToLower(doc.Name)
+		if simpleTerm != lowerTerm && strings.HasSuffix(lowerDocName, lowerTerm) {
+			rest := lowerDocName[:len(lowerDocName)-len(lowerTerm)]
+			if rest == "" || strings.ContainsAny(rest[len(rest)-1:], nameSeparators) ||
+				strings.ContainsAny(lowerTerm[:1], nameSeparators) {
+				score += scoreQualifiedName
+			}
+		}
+	}
+	if doc.Package == StandardLibrary {
+		score += scoreStandardLibrary
+	}
+	if isType(doc.Kind) {
+		score += scoreType
+	}
+	if used, ok := history[historyKey{doc.Language, doc.Name}]; ok {
+		weeks := now.Sub(used).Hours() / (24 * 7)
+		score += int(scoreRecentUse * math.Pow(0.5, max(weeks, 0)))
+	}
+	score -= min(depth(doc), maxDepthPenalty)
+	return score
+}
+
+// rankDocuments sorts documents by how well they match a query, best first.
+// Documents with the same score keep their order.
+func rankDocuments(docs []*SearchDocument, query string, history map[historyKey]time.Time, now time.Time) {
+	type scoredDocument struct {
+		doc   *SearchDocument
+		score int
+	}
+	scored := make([]scoredDocument, len(docs))
+	for i, doc := range docs {
+		scored[i] = scoredDocument{doc, scoreDocument(doc, query, history, now)}
+	}
+	slices.SortStableFunc(scored, func(a, b scoredDocument) int { return b.score - a.score })
+	for i := range scored {
+		docs[i] = scored[i].doc
+	}
+}
+
+// loadHistory returns when each document was last selected.
+func loadHistory(db *sql.DB) (map[historyKey]time.Time, error) {
+	rows, err := db.Query("SELECT language, name, used FROM history")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer rows.Close()
+	history := make(map[historyKey]time.Time)
+	for rows.Next() {
+		var key historyKey
+		var used int64
+		err := rows.Scan(&key.language, &key.name, &used)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		history[key] = time.Unix(used, 0)
+	}
+	return history, rows.Err()
+}
+
+// RecordUse records that the user selected a document, so it ranks higher in
+// later searches (in any version of its library).
+func RecordUse(db *sql.DB, doc *SearchDocument) error {
+	_, err := db.Exec(`
+		INSERT INTO history (language, name, used) VALUES (?, ?, ?)
+		ON CONFLICT (language, name) DO UPDATE SET used = excluded.used
+	`, doc.Language, doc.Name, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumps(t *testing.T) {
+	tests := map[string]string{
+		"HashMap":           "hm",
+		"HTTPServer":        "hs",
+		"get_user_by_id":    "gubi",
+		"XMLHttpRequest":    "xhr",
+		"ConcurrentHashMap": "chm",
+		"parse":             "p",
+	}
+	for name, expected := range tests {
+		if got := humps(name); got != expected {
+			t.Errorf("humps(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestRankDocuments(t *testing.T) {
+	doc := func(kind Kind, name string, pkg string) *SearchDocument {
+		return &SearchDocument{Language: "java", Kind: kind, Name: name, Path: "/src/" + name, Package: pkg}
+	}
+	hashMap := doc(Class, "java.util.HashMap", StandardLibrary)
+	hashMapper := doc(Class, "com.example.HashMapper", "com.example:example")
+	hashMapUtil := doc(Method, "com.example.Util.hashMap", "com.example:example")
+	concurrent := doc(Class, "java.util.concurrent.ConcurrentHashMap", StandardLibrary)
+	humpy := doc(Class, "com.example.HeapMonitor", "com.example:example")
+	now := time.Now()
+	tests := []struct {
+		query    string
+		history  map[historyKey]time.Time
+		expected []*SearchDocument
+	}{
+		// Exact match (with the same case first), prefix, then contains
+		{"HashMap", nil, []*SearchDocument{hashMap, hashMapUtil, hashMapper, concurrent, humpy}},
+		{"util.hashmap", nil, []*SearchDocument{hashMap, hashMapUtil, hashMapper, concurrent, humpy}},
+		{"HM", nil, []*SearchDocument{hashMap, hashMapper, humpy, concurrent, hashMapUtil}},
+		// A document that was just selected ranks higher, less so after a while
+		{"HashMap", map[historyKey]time.Time{{"java", hashMapper.Name}: now},
+			[]*SearchDocument{hashMapper, hashMap, hashMapUtil, concurrent, humpy}},
+		{"HashMap", map[historyKey]time.Time{{"java", hashMapper.Name}: now.Add(-30 * 24 * time.Hour)},
+			[]*SearchDocument{hashMap, hashMapUtil, hashMapper, concurrent, humpy}},
+	}
+	for _, test := range tests {
+		docs := []*SearchDocument{concurrent, hashMapUtil, hashMapper, humpy, hashMap}
+		rankDocuments(docs, test.query, test.history, now)
+		for i := range docs {
+			if docs[i] != test.expected[i] {
+				names := make([]string, len(docs))
+				for j, doc := range docs {
+					names[j] = doc.Name
+				}
+				t.Errorf("rankDocuments(%q) = %v", test.query, names)
+				break
+			}
+		}
+	}
+}
@@ -16,78 +16,133 @@ package common
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 
-	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-type Language int
+type Kind int
 
 const (
-	Java Language = iota
-	Python
-	Javascript
-	Go
+	Module Kind = iota
+	Class
+	Interface
+	Enum
+	Record
+	Type
+	Function
+	Method
+	Constant
+	Variable
 )
 
-func LanguageFromName(name string) Language {
+func KindFromName(name string) Kind {
 	switch strings.ToLower(name) {
-	case "java":
-		return Java
-	case "python":
-		return Python
-	case "javascript":
-		return Javascript
-	case "go":
-		return Go
+	case "module":
+		return Module
+	case "class":
+		return Class
+	case "interface":
+		return Interface
+	case "enum":
+		return Enum
+	case "record":
+		return Record
+	case "type":
+		return Type
+	case "function", "func":
+		return Function
+	case "method":
+		return Method
+	case "constant", "const":
+		return Constant
+	case "variable", "var":
+		return Variable
 	default:
 		return -1
 	}
 }
 
-func NameFromLanguage(language Language) string {
-	switch language {
-	case Java:
-		return "java"
-	case Python:
-		return "python"
-	case Javascript:
-		return "javascript"
-	case Go:
-		return "go"
+func NameFromKind(kind Kind) string {
+	switch kind {
+	case Module:
+		return "module"
+	case Class:
+		return "class"
+	case Interface:
+		return "interface"
+	case Enum:
+		return "enum"
+	case Record:
+		return "record"
+	case Type:
+		return "type"
+	case Function:
+		return "function"
+	case Method:
+		return "method"
+	case Constant:
+		return "constant"
+	case Variable:
+		return "variable"
 	default:
 		return ""
 	}
 }
 
+// Symbol is a definition found in a source file. Line is 1-based.
+type Symbol struct {
+	Name string
+	Kind Kind
+	Line int
+}
+
+// SearchDocument is an indexed module or symbol. ID is its row in the index
+// (0 until it is indexed), Line is 0 for whole files,
+// Package and Version identify the library (or StandardLibrary) it belongs to
+// (if known), and Root is the source root the file was found in.
 type SearchDocument struct {
+	ID       int64
 	Language Language
+	Kind     Kind
 	Name     string
 	Path     string
+	Line     int
+	Package  string
+	Version  string
+	Root     string
 }
 
-func MakeFuzzy(pattern string) string {
-	re := regexp.MustCompile(`\s+`)
-	return "%" + re.ReplaceAllString(pattern, "%") + "%"
-}
+var ErrFzfNotFound = errors.New("fzf not found in PATH, install fzf or use --format to print the results")
 
-func RunFzf(filterQuery string, text *bytes.Buffer) (string, string, error) {
+// RunFzf lets the user select a line of the text with fzf, and returns the
+// query they typed, the key they selected it with (one of expect, or "" for
+// enter) and the selected line.
+func RunFzf(filterQuery string, text *bytes.Buffer, expect []string, args ...string) (string, string, string, error) {
 	// Check if fzf is installed
 	_, err := exec.LookPath("fzf")
 	if err != nil {
-		panic(fmt.Errorf("fzf not found in PATH. Please install fzf to use this feature"))
+		return "", "", "", ErrFzfNotFound
 	}
 	// Build command
-	args := []string{"--print-query"}
+	args = append([]string{"--print-query"}, args...)
 	if filterQuery != "" {
 		args = append(args, "--query", filterQuery)
 	}
+	if len(expect) > 0 {
+		args = append(args, "--expect", strings.Join(expect, ","))
+	}
 	// Run fzf over the text
 	fzf := exec.Command("fzf", args...)
 	fzf.Stdin = text
@@ -95,63 +150,148 @@ func RunFzf(filterQuery string, text *bytes.Buffer) (string, string, error) {
 	fzf.Stdout = &output
 	fzf.Stderr = os.Stderr
 	if err := fzf.Run(); err != nil {
-		return "", "", err
-	}
-	// Parse the output
-	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
-	query := ""
-	selection := ""
-	switch len(lines) {
-	case 1:
-		selection = lines[0]
-	case 2:
-		query = lines[0]
-		selection = lines[1]
-	}
-	return query, selection, nil
+		return "", "", "", err
+	}
+	// Parse the output: the query, the key (with --expect) and the selection
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(expect) == 0 {
+		lines = slices.Insert(lines, 1, "")
+	}
+	if len(lines) < 3 {
+		return "", "", "", fmt.Errorf("unexpected fzf output: %q", output.String())
+	}
+	return lines[0], lines[1], lines[2], nil
 }
 
-func RunFzfSearchDocuments(filterQuery string, docs []*SearchDocument) (string, *SearchDocument, error) {
-	// Create a buffer of the document names
-	lines := make([]string, len(docs))
-	for i, doc := range docs {
-		lines[i] = strings.Join([]string{NameFromLanguage(doc.Language), doc.Name}, "\t")
+// packageLabel describes the library a document belongs to (e.g.
+// lodash@4.17.21), or "-" if it is not known.
+func packageLabel(doc *SearchDocument) string {
+	switch {
+	case doc.Package == "":
+		return "-"
+	case doc.Version == "":
+		return doc.Package
+	default:
+		return doc.Package + "@" + doc.Version
 	}
-	lines = Dedupe(lines)
-	slices.Sort(lines)
-	text := bytes.NewBufferString(strings.Join(lines, "\n"))
-	// Get the selected document name via fzf
-	filterQuery, selected, err := RunFzf(filterQuery, text)
-	if err != nil {
-		return "", nil, err
+}
+
+// rootLabel shortens the home directory in a root to ~.
+func rootLabel(root string) string {
+	if root == "" {
+		return "-"
+	}
+	home, err := os.UserHomeDir()
+	if err == nil && strings.HasPrefix(root, home+string(os.PathSeparator)) {
+		return "~" + root[len(home):]
 	}
-	selectedTokens := strings.Split(selected, "\t")
-	selectedName := selectedTokens[1]
-	// Find the document in the list
+	return root
+}
+
+// Keys to open the document selected in fzf in $EDITOR, or copy its path
+const (
+	FzfKeyEditor = "ctrl-o"
+	FzfKeyCopy   = "ctrl-y"
+)
+
+// shellQuote quotes a string for the shell that fzf runs commands with.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RunFzfSearchDocuments lets the user select a document in fzf, with a preview
+// of its code (from rtfm preview), and returns the query they typed and the
+// key they selected it with.
+func RunFzfSearchDocuments(filterQuery string, docs []*SearchDocument) (string, string, *SearchDocument, error) {
+	// Create a buffer of the document names, with the library and root they
+	// are from (as the same file can be in several projects). The hidden
+	// columns are the ID of the document and the line to scroll the preview to.
+	byID := make(map[string]*SearchDocument, len(docs))
+	seen := make(map[string]struct{}, len(docs))
+	lines := make([]string, 0, len(docs))
 	for _, doc := range docs {
-		if doc.Name == selectedName {
-			return filterQuery, doc, nil
+		label := strings.Join([]string{
+			string(doc.Language),
+			NameFromKind(doc.Kind),
+			doc.Name,
+			packageLabel(doc),
+			rootLabel(doc.Root),
+		}, "\t")
+		if _, ok := seen[label]; ok {
+			continue
 		}
+		seen[label] = struct{}{}
+		scroll := 0
+		if doc.Line > 0 {
+			scroll = doc.Line + HighlightHeaderLines
+		}
+		id := strconv.FormatInt(doc.ID, 10)
+		byID[id] = doc
+		lines = append(lines, fmt.Sprintf("%s\t%d\t%s", id, scroll, label))
+	}
+	text := bytes.NewBufferString(strings.Join(lines, "\n"))
+	// Get the selected document via fzf
+	executable, err := os.Executable()
+	if err != nil {
+		return "", "", nil, err
+	}
+	// Equal fzf scores keep the ranked order
+	filterQuery, key, selected, err := RunFzf(filterQuery, text, []string{FzfKeyEditor, FzfKeyCopy},
+		"--tiebreak", "index",
+		"--delimiter", "\t",
+		"--with-nth", "3..",
+		"--preview", shellQuote(executable)+" preview {1}",
+		"--preview-window", "right,60%,+{2}/3",
+		"--header", fmt.Sprintf("enter: view, %s: open in $EDITOR, %s: copy path", FzfKeyEditor, FzfKeyCopy),
+	)
+	if err != nil {
+		return "", "", nil, err
 	}
-	return filterQuery, nil, fmt.Errorf("document not found: %s", selected)
+	id, _, _ := strings.Cut(selected, "\t")
+	doc, ok := byID[id]
+	if !ok {
+		return filterQuery, key, nil, fmt.Errorf("document not found: %s", selected)
+	}
+	return filterQuery, key, doc, nil
 }
 
 func pathAsComment(language Language, path string) string {
-	switch language {
-	case Java, Javascript, Go:
-		return fmt.Sprintf("// %s", path)
-	default:
-		return fmt.Sprintf("# %s", path)
+	if indexer, ok := LookupIndexer(language); ok {
+		return fmt.Sprintf("%s %s", indexer.CommentPrefix(), path)
 	}
+	return fmt.Sprintf("# %s", path)
 }
 
-func HighlightCode(code string, language Language, path string) (string, error) {
+// HighlightHeaderLines is the number of lines HighlightCode adds before the code
+const HighlightHeaderLines = 2
+
+// TerminalFormatter highlights code with the escape sequences of 256 color
+// terminals.
+var TerminalFormatter = formatters.TTY256
+
+// HighlightCode highlights code with a chroma formatter (e.g. TerminalFormatter
+// or an HTML formatter). If path is set, it is prepended as a comment.
+func HighlightCode(code string, language Language, path string, formatter chroma.Formatter) (string, error) {
 	// Prepend the path as a comment
-	code = pathAsComment(language, path) + "\n\n" + code
+	if path != "" {
+		code = pathAsComment(language, path) + "\n\n" + code
+	}
+	// Find the lexer of the language
+	name := string(language)
+	if indexer, ok := LookupIndexer(language); ok {
+		name = indexer.Lexer()
+	}
+	lexer := lexers.Get(name)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
 	// Highlight the code
-	languageName := NameFromLanguage(language)
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
 	var buffer bytes.Buffer
-	err := quick.Highlight(&buffer, code, languageName, "terminal256", "monokai")
+	err = formatter.Format(&buffer, styles.Get("monokai"), iterator)
 	if err != nil {
 		return "", err
 	}
@@ -170,8 +310,14 @@ func Dedupe(input []string) []string {
 	return result
 }
 
-func DisplayInPager(text string) error {
-	cmd := exec.Command("less")
+// DisplayInPager shows the text in less, scrolled to the given line (1-based,
+// or 0 to start at the top).
+func DisplayInPager(text string, line int) error {
+	args := []string{}
+	if line > 0 {
+		args = append(args, fmt.Sprintf("+%dg", line))
+	}
+	cmd := exec.Command("less", args...)
 	cmd.Stdin = strings.NewReader(text)
 	cmd.Stdout = os.Stdout
 	if err := cmd.Run(); err != nil {
@@ -179,3 +325,52 @@ func DisplayInPager(text string) error {
 	}
 	return nil
 }
+
+// OpenInEditor opens the file in $EDITOR (or vi), at the given line if it is
+// known. Entries in archives are opened from a read-only copy.
+func OpenInEditor(path string, line int) error {
+	path, err := MaterializeFile(path)
+	if err != nil {
+		return err
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	args := editor[1:]
+	if line > 0 {
+		args = append(args, fmt.Sprintf("+%d", line))
+	}
+	args = append(args, path)
+	cmd := exec.Command(editor[0], args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// CopyToClipboard copies text with the first clipboard tool that is installed,
+// or with an OSC 52 escape sequence that most terminals support.
+func CopyToClipboard(text string) error {
+	tools := [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"pbcopy"},
+	}
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("no clipboard tool found: %w", err)
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
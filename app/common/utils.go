@@ -15,14 +15,14 @@
 package common
 
 import (
-	"os"
 	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Exists reports whether a file (or an entry in an archive) exists.
 func Exists(file string) bool {
-	if _, err := os.Stat(file); err == nil {
+	if _, err := StatFile(file); err == nil {
 		return true
 	}
 	return false
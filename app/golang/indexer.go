@@ -16,47 +16,154 @@ package golang
 
 import (
 	"fmt"
+	"go/parser"
+	"go/token"
 	"log/slog"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 
 	"github.com/brandtg/rtfm/app/common"
 )
 
-func Index() error {
-	slog.Info("Indexing Go modules...")
-	// Connect to the database
-	db, err := common.OpenDB()
-	if err != nil {
-		return fmt.Errorf("error opening database: %w", err)
-	}
-	defer db.Close()
-	// Find GOPATH
+const Language common.Language = "go"
+
+type Indexer struct{}
+
+func init() {
+	common.Register(Indexer{})
+}
+
+func (Indexer) Language() common.Language {
+	return Language
+}
+
+func (Indexer) CommentPrefix() string {
+	return "//"
+}
+
+func (Indexer) Lexer() string {
+	return "go"
+}
+
+// Roots returns the module directories in the GOPATH module cache, and the
+// standard library (named by import path, e.g. net/http) in GOROOT.
+func (Indexer) Roots(opts common.IndexOptions) ([]*common.Root, error) {
+	roots := make([]*common.Root, 0)
+	// Find modules in the GOPATH (the standard library is indexed without one)
+	modules := make([]string, 0)
 	gopath, err := findGoPath()
 	if err != nil {
-		return fmt.Errorf("error resolving GOPATH: %w", err)
-	}
-	// Find modules in the GOPATH
-	modules, err := findModules(gopath)
-	if err != nil {
-		return fmt.Errorf("error finding modules: %w", err)
+		slog.Warn("Error resolving GOPATH", "error", err)
+	} else {
+		modules, err = findModules(gopath)
+		if err != nil {
+			return nil, fmt.Errorf("error finding modules: %w", err)
+		}
 	}
 	for _, module := range modules {
-		// Find code files in the module
-		codeFiles, err := findCodeFiles(module)
+		moduleName, err := findModuleName(module)
 		if err != nil {
-			slog.Error("Error finding code files", "module", module, "error", err)
+			slog.Error("Error finding module name", "module", module, "error", err)
 			continue
 		}
-		// Index the documents
-		err = common.IndexDocuments(db, codeFiles)
-		if err != nil {
-			return fmt.Errorf("error indexing documents: %w", err)
+		roots = append(roots, &common.Root{
+			Path:    filepath.Dir(module),
+			Package: moduleName,
+			Version: parseModuleVersion(module),
+			Source:  &goRoot{importPath: moduleName},
+		})
+	}
+	// Standard library
+	goroot, err := findGoRoot()
+	if err != nil {
+		slog.Warn("Error finding the standard library", "error", err)
+		return roots, nil
+	}
+	version := findGoVersion(goroot)
+	slog.Info("Found Go standard library", "goroot", goroot, "version", version)
+	return append(roots, &common.Root{
+		Path:    filepath.Join(goroot, "src"),
+		Package: common.StandardLibrary,
+		Version: version,
+		Source:  &goRoot{},
+	}), nil
+}
+
+// goRoot is the source of a root: the import path of its directory, and the
+// file of each package that its document points to (found by Files).
+type goRoot struct {
+	importPath   string
+	packageFiles map[string]bool
+}
+
+// parseModuleVersion returns the version of a module in the module cache,
+// which is laid out as pkg/mod/<module>@<version>, or "" for other modules.
+func parseModuleVersion(goMod string) string {
+	parts := strings.Split(filepath.ToSlash(filepath.Dir(goMod)), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if _, version, ok := strings.Cut(parts[i], "@"); ok {
+			return version
 		}
 	}
-	return nil
+	return ""
+}
+
+func (Indexer) Files(root *common.Root) ([]string, error) {
+	source := root.Source.(*goRoot)
+	codeFiles, err := findCodeFiles(root.Path, source.importPath)
+	if err != nil {
+		return nil, err
+	}
+	source.packageFiles = findPackageFiles(codeFiles)
+	return codeFiles, nil
+}
+
+// Parse creates search documents for the declarations in a file, named by
+// the import path of its package (e.g. net/http.Client and
+// net/http.(*Client).Do), and for the package itself (e.g. net/http) if the
+// file is its package file.
+func (Indexer) Parse(root *common.Root, codeFile string, code []byte) ([]*common.SearchDocument, error) {
+	source := root.Source.(*goRoot)
+	relPath, err := filepath.Rel(root.Path, codeFile)
+	if err != nil {
+		return nil, err
+	}
+	packagePath := path.Join(source.importPath, path.Dir(filepath.ToSlash(relPath)))
+	symbols, err := parseGoFile(packagePath, codeFile, code)
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]*common.SearchDocument, 0, len(symbols)+1)
+	// Every file needs a document, to record that it was indexed
+	if source.packageFiles[codeFile] || len(symbols) == 0 {
+		documents = append(documents, &common.SearchDocument{
+			Language: Language,
+			Kind:     common.Module,
+			Name:     packagePath,
+			Path:     codeFile,
+			Package:  root.Package,
+			Version:  root.Version,
+			Root:     root.Path,
+		})
+	}
+	for _, symbol := range symbols {
+		documents = append(documents, &common.SearchDocument{
+			Language: Language,
+			Kind:     symbol.Kind,
+			Name:     symbol.Name,
+			Path:     codeFile,
+			Line:     symbol.Line,
+			Package:  root.Package,
+			Version:  root.Version,
+			Root:     root.Path,
+		})
+	}
+	return documents, nil
 }
 
 func findGoPath() (string, error) {
@@ -70,6 +177,35 @@ func findGoPath() (string, error) {
 	return gopath, nil
 }
 
+func findGoRoot() (string, error) {
+	goroot := ""
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err == nil {
+		goroot = strings.TrimSpace(string(out))
+	}
+	if goroot == "" {
+		goroot = runtime.GOROOT() // GOROOT that rtfm was built with
+	}
+	if _, err := os.Stat(filepath.Join(goroot, "src")); err != nil {
+		return "", fmt.Errorf("GOROOT source directory does not exist: %s", goroot)
+	}
+	return goroot, nil
+}
+
+func findGoVersion(goroot string) string {
+	// The first line of $GOROOT/VERSION is the toolchain version (e.g. go1.24.1)
+	data, err := os.ReadFile(filepath.Join(goroot, "VERSION"))
+	if err == nil {
+		version, _, _ := strings.Cut(string(data), "\n")
+		return strings.TrimSpace(version)
+	}
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	return runtime.Version()
+}
+
 func findModules(gopath string) ([]string, error) {
 	acc := make([]string, 0)
 	err := filepath.Walk(gopath, func(path string, info os.FileInfo, err error) error {
@@ -104,18 +240,24 @@ func findModuleName(path string) (string, error) {
 	return moduleName, nil
 }
 
-func findCodeFiles(module string) ([]*common.SearchDocument, error) {
-	// Find the module name
-	moduleName, err := findModuleName(module)
-	if err != nil {
-		return nil, fmt.Errorf("error finding module name: %w", err)
-	}
-	// Find the code files
-	moduleDir := filepath.Dir(module)
+// findCodeFiles finds the Go files in a module, where importPath is the
+// import path of the module's root directory.
+func findCodeFiles(moduleDir string, importPath string) ([]string, error) {
 	codeFiles := make([]string, 0)
-	err = filepath.Walk(
+	err := filepath.Walk(
 		moduleDir,
 		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && (info.Name() == "testdata" || info.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			// The standard library (which has no import path prefix) also
+			// contains the toolchain commands, which aren't importable
+			if info.IsDir() && importPath == "" && path == filepath.Join(moduleDir, "cmd") {
+				return filepath.SkipDir
+			}
 			if !info.IsDir() &&
 				filepath.Ext(path) == ".go" &&
 				!strings.Contains(filepath.Base(path), "_test.go") {
@@ -124,17 +266,43 @@ func findCodeFiles(module string) ([]*common.SearchDocument, error) {
 			return nil
 		})
 	if err != nil {
-		return nil, fmt.Errorf("error walking the path %s: %w", module, err)
-	}
-	// Construct search documents
-	docs := make([]*common.SearchDocument, len(codeFiles))
-	for i, codeFile := range codeFiles {
-		name := strings.Replace(codeFile, moduleDir, moduleName, 1)
-		docs[i] = &common.SearchDocument{
-			Language: common.Go,
-			Name:     name,
-			Path:     codeFile,
+		return nil, fmt.Errorf("error walking the path %s: %w", moduleDir, err)
+	}
+	return codeFiles, nil
+}
+
+// findPackageFiles picks one file in each directory to document its package:
+// doc.go, or else the first file with the package's doc comment, or else the
+// first file, so that packages without a doc comment can be found too.
+func findPackageFiles(codeFiles []string) map[string]bool {
+	dirs := make(map[string][]string)
+	order := make([]string, 0)
+	for _, codeFile := range codeFiles {
+		dir := filepath.Dir(codeFile)
+		if _, ok := dirs[dir]; !ok {
+			order = append(order, dir)
+		}
+		dirs[dir] = append(dirs[dir], codeFile)
+	}
+	packageFiles := make(map[string]bool, len(order))
+	for _, dir := range order {
+		packageFiles[findPackageFile(dirs[dir])] = true
+	}
+	return packageFiles
+}
+
+func findPackageFile(codeFiles []string) string {
+	for _, codeFile := range codeFiles {
+		if filepath.Base(codeFile) == "doc.go" {
+			return codeFile
+		}
+	}
+	fset := token.NewFileSet()
+	for _, codeFile := range codeFiles {
+		file, err := parser.ParseFile(fset, codeFile, nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err == nil && file.Doc != nil {
+			return codeFile
 		}
 	}
-	return docs, nil
+	return codeFiles[0]
 }
@@ -0,0 +1,64 @@
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func TestParsePackageDocuments(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		// doc.go documents the package, even without a doc comment
+		"docfile/a.go":   "// Package docfile does things.\npackage docfile\n\nfunc A() {}\n",
+		"docfile/doc.go": "package docfile\n",
+		// Otherwise the file with the doc comment
+		"comment/a.go": "package comment\n\nfunc A() {}\n",
+		"comment/b.go": "// Package comment does things.\npackage comment\n\nfunc B() {}\n",
+		// Otherwise the first file
+		"plain/a.go": "package plain\n\nfunc A() {}\n",
+		"plain/b.go": "package plain\n\nfunc B() {}\n",
+	}
+	for name, code := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(code), 0o644)
+	}
+	root := &common.Root{Path: dir, Source: &goRoot{importPath: "example.com/foo"}}
+	codeFiles, err := Indexer{}.Files(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packages := make(map[string]string)
+	for _, codeFile := range codeFiles {
+		rel, _ := filepath.Rel(dir, codeFile)
+		documents, err := Indexer{}.Parse(root, codeFile, []byte(files[filepath.ToSlash(rel)]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, doc := range documents {
+			if doc.Kind != common.Module {
+				continue
+			}
+			if previous, ok := packages[doc.Name]; ok {
+				t.Errorf("%s: documented by %s and %s", doc.Name, previous, doc.Path)
+			}
+			packages[doc.Name] = doc.Path
+		}
+	}
+	expected := map[string]string{
+		"example.com/foo/docfile": "docfile/doc.go",
+		"example.com/foo/comment": "comment/b.go",
+		"example.com/foo/plain":   "plain/a.go",
+	}
+	if len(packages) != len(expected) {
+		t.Errorf("unexpected packages: %v", packages)
+	}
+	for name, path := range expected {
+		if packages[name] != filepath.Join(dir, filepath.FromSlash(path)) {
+			t.Errorf("%s: expected %s, got %s", name, path, packages[name])
+		}
+	}
+}
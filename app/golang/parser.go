@@ -0,0 +1,114 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// receiverName returns the name of a method's receiver type, in parentheses
+// if it is a pointer (e.g. (*Client) or List for a List[T]).
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		if name := receiverName(t.X); name != "" {
+			return "(*" + name + ")"
+		}
+	case *ast.ParenExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// parseGoFile finds the top-level declarations in a file, named like
+// net/http.Client and net/http.(*Client).Do. Files with syntax errors are
+// parsed as far as possible.
+func parseGoFile(packagePath string, filename string, code []byte) ([]common.Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, code, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil {
+		return nil, err
+	}
+	if err != nil {
+		slog.Debug("Error parsing Go file", "path", filename, "error", err)
+	}
+	symbols := make([]common.Symbol, 0)
+	add := func(name *ast.Ident, prefix string, kind common.Kind) {
+		if name == nil || name.Name == "_" {
+			return
+		}
+		symbols = append(symbols, common.Symbol{
+			Name: packagePath + "." + prefix + name.Name,
+			Kind: kind,
+			Line: fset.Position(name.Pos()).Line,
+		})
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			switch {
+			case decl.Recv == nil && decl.Name.Name == "init":
+				// Packages can have several init functions, which can't be called
+			case decl.Recv == nil:
+				add(decl.Name, "", common.Function)
+			case len(decl.Recv.List) > 0:
+				if receiver := receiverName(decl.Recv.List[0].Type); receiver != "" {
+					add(decl.Name, receiver+".", common.Method)
+				}
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					kind := common.Variable
+					if decl.Tok == token.CONST {
+						kind = common.Constant
+					}
+					for _, name := range spec.Names {
+						add(name, "", kind)
+					}
+				case *ast.TypeSpec:
+					iface, ok := spec.Type.(*ast.InterfaceType)
+					if !ok {
+						add(spec.Name, "", common.Type)
+						continue
+					}
+					add(spec.Name, "", common.Interface)
+					// Interface methods (embedded interfaces and type
+					// constraints have no names)
+					for _, method := range iface.Methods.List {
+						if _, ok := method.Type.(*ast.FuncType); ok {
+							for _, name := range method.Names {
+								add(name, spec.Name.Name+".", common.Method)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
@@ -0,0 +1,102 @@
+package golang
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testFile = `// Package http provides HTTP client and server implementations.
+package http
+
+import "io"
+
+const DefaultMaxHeaderBytes = 1 << 20
+
+const (
+	MethodGet  = "GET"
+	MethodPost = "POST"
+	_          = 0
+)
+
+var ErrNotSupported, errPrivate = 1, 2
+
+// func NotAFunction()
+type Client struct {
+	Timeout int
+}
+
+type (
+	Handler interface {
+		ServeHTTP(w ResponseWriter, r *Request)
+		io.Closer
+	}
+	Header map[string][]string
+	List[T any] []T
+)
+
+func init() {}
+
+func Get(url string) (*Response, error) {
+	s := ` + "`" + `
+func NotAFunctionEither()
+` + "`" + `
+	return nil, nil
+}
+
+func (c *Client) Do(req *Request) (*Response, error) { return nil, nil }
+
+func (h Header) Get(key string) string { return "" }
+
+func (l *List[T]) Push(v T) {}
+`
+
+func TestParseGoFile(t *testing.T) {
+	symbols, err := parseGoFile("net/http", "client.go", []byte(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for _, symbol := range symbols {
+		fmt.Fprintf(&b, "%d %d %s\n", symbol.Line, symbol.Kind, symbol.Name)
+	}
+	expected := strings.Join([]string{
+		"6 8 net/http.DefaultMaxHeaderBytes",
+		"9 8 net/http.MethodGet",
+		"10 8 net/http.MethodPost",
+		"14 9 net/http.ErrNotSupported",
+		"14 9 net/http.errPrivate",
+		"17 5 net/http.Client",
+		"22 2 net/http.Handler",
+		"23 7 net/http.Handler.ServeHTTP",
+		"26 5 net/http.Header",
+		"27 5 net/http.List",
+		"32 6 net/http.Get",
+		"39 7 net/http.(*Client).Do",
+		"41 7 net/http.Header.Get",
+		"43 7 net/http.(*List).Push",
+	}, "\n") + "\n"
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestParseGoFileErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected int
+	}{
+		// Declarations before a syntax error are still found
+		{"package foo\n\nfunc Foo() {}\n\nfunc (", 1},
+		{"not go", 0},
+	}
+	for _, test := range tests {
+		symbols, err := parseGoFile("foo", "foo.go", []byte(test.code))
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.code, err)
+		}
+		if len(symbols) != test.expected {
+			t.Errorf("%q: expected %d symbols, got %v", test.code, test.expected, symbols)
+		}
+	}
+}
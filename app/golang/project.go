@@ -0,0 +1,57 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Dependencies reads the module versions from go.sum, which lists every module
+// in the project's build graph.
+func (Indexer) Dependencies(projectDir string) ([]common.Dependency, error) {
+	file, err := os.Open(filepath.Join(projectDir, "go.sum"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseGoSum(bufio.NewScanner(file))
+}
+
+// parseGoSum parses lines like "<module> <version>[/go.mod] <hash>".
+func parseGoSum(scanner *bufio.Scanner) ([]common.Dependency, error) {
+	acc := make([]common.Dependency, 0)
+	seen := make(map[string]struct{})
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		version := strings.TrimSuffix(fields[1], "/go.mod")
+		if _, ok := seen[fields[0]+"@"+version]; ok {
+			continue
+		}
+		seen[fields[0]+"@"+version] = struct{}{}
+		acc = append(acc, common.Dependency{Language: Language, Package: fields[0], Version: version})
+	}
+	return acc, scanner.Err()
+}
@@ -0,0 +1,39 @@
+package golang
+
+import (
+	"bufio"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseGoSum(t *testing.T) {
+	tests := []struct {
+		goSum    string
+		expected []string
+	}{
+		{
+			"github.com/spf13/cobra v1.9.1 h1:abc=\ngithub.com/spf13/cobra v1.9.1/go.mod h1:def=\n",
+			[]string{"github.com/spf13/cobra@v1.9.1"},
+		},
+		// Modules only needed for their go.mod are part of the build graph too
+		{
+			"golang.org/x/sys v0.30.0/go.mod h1:abc=\ngolang.org/x/sys v0.33.0 h1:def=\n",
+			[]string{"golang.org/x/sys@v0.30.0", "golang.org/x/sys@v0.33.0"},
+		},
+		{"\nnot a go.sum line\n", []string{}},
+	}
+	for _, test := range tests {
+		dependencies, err := parseGoSum(bufio.NewScanner(strings.NewReader(test.goSum)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := make([]string, 0)
+		for _, dependency := range dependencies {
+			actual = append(actual, dependency.Package+"@"+dependency.Version)
+		}
+		if !slices.Equal(actual, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.goSum, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Access flags (JVMS §4.1, §4.5, §4.6)
+const (
+	accPublic     = 0x0001
+	accPrivate    = 0x0002
+	accProtected  = 0x0004
+	accStatic     = 0x0008
+	accFinal      = 0x0010
+	accVolatile   = 0x0040
+	accBridge     = 0x0040
+	accTransient  = 0x0080
+	accVarargs    = 0x0080
+	accNative     = 0x0100
+	accInterface  = 0x0200
+	accAbstract   = 0x0400
+	accSynthetic  = 0x1000
+	accAnnotation = 0x2000
+	accEnum       = 0x4000
+)
+
+// Constant pool tags (JVMS §4.4)
+const (
+	constantUtf8               = 1
+	constantInteger            = 3
+	constantFloat              = 4
+	constantLong               = 5
+	constantDouble             = 6
+	constantClass              = 7
+	constantString             = 8
+	constantFieldref           = 9
+	constantMethodref          = 10
+	constantInterfaceMethodref = 11
+	constantNameAndType        = 12
+	constantMethodHandle       = 15
+	constantMethodType         = 16
+	constantDynamic            = 17
+	constantInvokeDynamic      = 18
+	constantModule             = 19
+	constantPackage            = 20
+)
+
+// classMember is a field or method.
+type classMember struct {
+	AccessFlags    uint16
+	Name           string
+	Descriptor     string
+	Annotations    []string
+	Exceptions     []string
+	ParameterNames []string
+	ConstantValue  string
+}
+
+// classFile is the API of a compiled class: everything but the code.
+type classFile struct {
+	AccessFlags uint16
+	Name        string // Binary name, e.g. java/util/Map$Entry
+	SuperName   string
+	Interfaces  []string
+	Fields      []*classMember
+	Methods     []*classMember
+	Annotations []string
+	// Access flags from the InnerClasses attribute, for nested classes
+	InnerAccessFlags uint16
+	IsInner          bool
+}
+
+type constant struct {
+	Tag   byte
+	Value string // Utf8 text, or the formatted value of a numeric constant
+	Index uint16 // Class, String, ... (index of a Utf8 constant)
+}
+
+type classReader struct {
+	data []byte
+	pos  int
+	err  error
+	pool []constant
+}
+
+var errTruncated = errors.New("truncated class file")
+
+func (r *classReader) u1() byte {
+	if r.err != nil || r.pos+1 > len(r.data) {
+		r.err = errTruncated
+		return 0
+	}
+	v := r.data[r.pos]
+	r.pos++
+	return v
+}
+
+func (r *classReader) u2() uint16 {
+	if r.err != nil || r.pos+2 > len(r.data) {
+		r.err = errTruncated
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.data[r.pos:])
+	r.pos += 2
+	return v
+}
+
+func (r *classReader) u4() uint32 {
+	if r.err != nil || r.pos+4 > len(r.data) {
+		r.err = errTruncated
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v
+}
+
+func (r *classReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = errTruncated
+		return nil
+	}
+	v := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return v
+}
+
+func (r *classReader) utf8(index uint16) string {
+	if int(index) >= len(r.pool) || r.pool[index].Tag != constantUtf8 {
+		return ""
+	}
+	return r.pool[index].Value
+}
+
+func (r *classReader) className(index uint16) string {
+	if int(index) >= len(r.pool) || r.pool[index].Tag != constantClass {
+		return ""
+	}
+	return r.utf8(r.pool[index].Index)
+}
+
+func (r *classReader) readConstantPool() {
+	count := int(r.u2())
+	r.pool = make([]constant, count)
+	for i := 1; i < count && r.err == nil; i++ {
+		tag := r.u1()
+		c := constant{Tag: tag}
+		switch tag {
+		case constantUtf8:
+			// Modified UTF-8 is close enough to UTF-8 for names and signatures
+			c.Value = string(r.bytes(int(r.u2())))
+		case constantInteger:
+			c.Value = fmt.Sprint(int32(r.u4()))
+		case constantFloat:
+			c.Value = fmt.Sprintf("%gf", math.Float32frombits(r.u4()))
+		case constantLong:
+			c.Value = fmt.Sprintf("%dL", int64(uint64(r.u4())<<32|uint64(r.u4())))
+		case constantDouble:
+			c.Value = fmt.Sprint(math.Float64frombits(uint64(r.u4())<<32 | uint64(r.u4())))
+		case constantClass, constantString, constantMethodType, constantModule, constantPackage:
+			c.Index = r.u2()
+		case constantFieldref, constantMethodref, constantInterfaceMethodref,
+			constantNameAndType, constantDynamic, constantInvokeDynamic:
+			r.u4()
+		case constantMethodHandle:
+			r.u1()
+			r.u2()
+		default:
+			r.err = fmt.Errorf("invalid constant pool tag %d", tag)
+		}
+		r.pool[i] = c
+		// Long and double constants take two entries
+		if tag == constantLong || tag == constantDouble {
+			i++
+		}
+	}
+}
+
+// skipElementValue skips an annotation element value (JVMS §4.7.16.1).
+func (r *classReader) skipElementValue() {
+	switch r.u1() {
+	case 'e':
+		r.u2()
+		r.u2()
+	case '@':
+		r.readAnnotation()
+	case '[':
+		count := int(r.u2())
+		for i := 0; i < count && r.err == nil; i++ {
+			r.skipElementValue()
+		}
+	default:
+		r.u2()
+	}
+}
+
+// readAnnotation reads an annotation and returns its type descriptor.
+func (r *classReader) readAnnotation() string {
+	descriptor := r.utf8(r.u2())
+	count := int(r.u2())
+	for i := 0; i < count && r.err == nil; i++ {
+		r.u2()
+		r.skipElementValue()
+	}
+	return descriptor
+}
+
+// readAttributes reads the attributes of a class or member, calling handle for
+// each one with a reader over the attribute's contents.
+func (r *classReader) readAttributes(handle func(name string, attr *classReader)) {
+	count := int(r.u2())
+	for i := 0; i < count && r.err == nil; i++ {
+		name := r.utf8(r.u2())
+		data := r.bytes(int(r.u4()))
+		if r.err != nil {
+			return
+		}
+		handle(name, &classReader{data: data, pool: r.pool})
+	}
+}
+
+func (r *classReader) readAnnotations(attr *classReader) []string {
+	acc := make([]string, 0)
+	count := int(attr.u2())
+	for i := 0; i < count && attr.err == nil; i++ {
+		acc = append(acc, attr.readAnnotation())
+	}
+	return acc
+}
+
+func (r *classReader) readMembers() []*classMember {
+	count := int(r.u2())
+	members := make([]*classMember, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		member := &classMember{
+			AccessFlags: r.u2(),
+			Name:        r.utf8(r.u2()),
+			Descriptor:  r.utf8(r.u2()),
+		}
+		r.readAttributes(func(name string, attr *classReader) {
+			switch name {
+			case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+				member.Annotations = append(member.Annotations, r.readAnnotations(attr)...)
+			case "Exceptions":
+				exceptionCount := int(attr.u2())
+				for j := 0; j < exceptionCount && attr.err == nil; j++ {
+					member.Exceptions = append(member.Exceptions, r.className(attr.u2()))
+				}
+			case "MethodParameters":
+				parameterCount := int(attr.u1())
+				for j := 0; j < parameterCount && attr.err == nil; j++ {
+					member.ParameterNames = append(member.ParameterNames, r.utf8(attr.u2()))
+					attr.u2()
+				}
+			case "ConstantValue":
+				index := attr.u2()
+				if int(index) < len(r.pool) {
+					c := r.pool[index]
+					if c.Tag == constantString {
+						member.ConstantValue = fmt.Sprintf("%q", r.utf8(c.Index))
+					} else {
+						member.ConstantValue = c.Value
+					}
+				}
+			}
+		})
+		members = append(members, member)
+	}
+	return members
+}
+
+// parseClassFile reads the declarations of a compiled class (JVMS §4).
+func parseClassFile(data []byte) (*classFile, error) {
+	r := &classReader{data: data}
+	if r.u4() != 0xCAFEBABE {
+		return nil, errors.New("not a class file")
+	}
+	r.u2() // minor version
+	r.u2() // major version
+	r.readConstantPool()
+	class := &classFile{
+		AccessFlags: r.u2(),
+		Name:        r.className(r.u2()),
+		SuperName:   r.className(r.u2()),
+	}
+	interfaceCount := int(r.u2())
+	for i := 0; i < interfaceCount && r.err == nil; i++ {
+		class.Interfaces = append(class.Interfaces, r.className(r.u2()))
+	}
+	class.Fields = r.readMembers()
+	class.Methods = r.readMembers()
+	r.readAttributes(func(name string, attr *classReader) {
+		switch name {
+		case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+			class.Annotations = append(class.Annotations, r.readAnnotations(attr)...)
+		case "InnerClasses":
+			count := int(attr.u2())
+			for i := 0; i < count && attr.err == nil; i++ {
+				inner := r.className(attr.u2())
+				attr.u2() // outer class
+				attr.u2() // inner name
+				flags := attr.u2()
+				if inner == class.Name {
+					class.InnerAccessFlags = flags
+					class.IsInner = true
+				}
+			}
+		}
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	if class.Name == "" {
+		return nil, errors.New("class file has no name")
+	}
+	return class, nil
+}
+
+// parseFieldType converts a field descriptor (e.g. [Ljava/lang/String;) to a
+// Java type, returning the type and the rest of the descriptor.
+func parseFieldType(descriptor string) (string, string) {
+	if descriptor == "" {
+		return "", ""
+	}
+	switch descriptor[0] {
+	case 'B':
+		return "byte", descriptor[1:]
+	case 'C':
+		return "char", descriptor[1:]
+	case 'D':
+		return "double", descriptor[1:]
+	case 'F':
+		return "float", descriptor[1:]
+	case 'I':
+		return "int", descriptor[1:]
+	case 'J':
+		return "long", descriptor[1:]
+	case 'S':
+		return "short", descriptor[1:]
+	case 'Z':
+		return "boolean", descriptor[1:]
+	case 'V':
+		return "void", descriptor[1:]
+	case '[':
+		elem, rest := parseFieldType(descriptor[1:])
+		return elem + "[]", rest
+	case 'L':
+		end := strings.IndexByte(descriptor, ';')
+		if end < 0 {
+			return javaTypeName(descriptor[1:]), ""
+		}
+		return javaTypeName(descriptor[1:end]), descriptor[end+1:]
+	default:
+		return "", ""
+	}
+}
+
+// parseMethodDescriptor converts a method descriptor (e.g. (ILjava/util/List;)V)
+// to its parameter and return types.
+func parseMethodDescriptor(descriptor string) ([]string, string) {
+	params := make([]string, 0)
+	rest := strings.TrimPrefix(descriptor, "(")
+	for rest != "" && rest[0] != ')' {
+		var param string
+		param, rest = parseFieldType(rest)
+		if param == "" {
+			break
+		}
+		params = append(params, param)
+	}
+	returnType, _ := parseFieldType(strings.TrimPrefix(rest, ")"))
+	return params, returnType
+}
+
+// javaTypeName converts a binary class name to a Java name, dropping the
+// package of java.lang classes (e.g. java/util/Map$Entry -> java.util.Map.Entry).
+func javaTypeName(binaryName string) string {
+	name := strings.ReplaceAll(strings.ReplaceAll(binaryName, "/", "."), "$", ".")
+	if rest, ok := strings.CutPrefix(name, "java.lang."); ok && !strings.Contains(rest, ".") {
+		return rest
+	}
+	return name
+}
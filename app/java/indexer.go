@@ -15,13 +15,12 @@
 package java
 
 import (
-	"archive/zip"
-	"database/sql"
 	"fmt"
-	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -29,44 +28,148 @@ import (
 	"github.com/brandtg/rtfm/app/common"
 )
 
-func Index() error {
-	slog.Info("Indexing Java classes...")
-	// Connect to the database
-	db, err := common.OpenDB()
+const Language common.Language = "java"
+
+type Indexer struct{}
+
+func init() {
+	common.Register(Indexer{})
+}
+
+func (Indexer) Language() common.Language {
+	return Language
+}
+
+func (Indexer) CommentPrefix() string {
+	return "//"
+}
+
+func (Indexer) Lexer() string {
+	return "java"
+}
+
+// Roots returns the artifacts in the Maven and Gradle caches, and the JDK
+// source archive.
+func (Indexer) Roots(opts common.IndexOptions) ([]*common.Root, error) {
+	artifacts, err := findJavaArtifacts()
 	if err != nil {
-		return fmt.Errorf("error opening database: %w", err)
-	}
-	defer db.Close()
-	// Extract Java classes from the artifacts
-	slog.Info("Processing Java artifacts...")
-	err = processJavaArtifacts()
+		return nil, fmt.Errorf("error finding Java artifacts: %w", err)
+	}
+	roots := make([]*common.Root, 0, len(artifacts)+1)
+	for _, artifact := range artifacts {
+		roots = append(roots, &common.Root{
+			Path:    artifact.Path,
+			Package: artifact.Package(),
+			Version: artifact.Version,
+			Source:  artifact,
+		})
+	}
+	// JDK classes
+	version, err := findJavaVersion()
 	if err != nil {
-		return fmt.Errorf("error processing Java artifacts: %w", err)
+		slog.Warn("Error finding Java version", "error", err)
+		return roots, nil
 	}
-	// JDK Classes
-	slog.Info("Processing JDK classes...")
-	err = processJDKClasses()
+	archive, err := findJDKSourceArchive(version)
 	if err != nil {
-		return fmt.Errorf("error processing JDK classes: %w", err)
+		slog.Warn("Error finding JDK source archive", "error", err)
+		return roots, nil
 	}
-	// Index the class files
-	slog.Info("Indexing class files...")
-	err = indexClassFiles(db)
+	return append(roots, &common.Root{Path: archive, Package: common.StandardLibrary, Version: version}), nil
+}
+
+// Files lists the Java files of sources jars and the JDK sources in place,
+// and generates stubs for binary jars.
+func (Indexer) Files(root *common.Root) ([]string, error) {
+	var paths []string
+	var err error
+	if artifact, ok := root.Source.(*MavenCoordinates); ok && isBinaryJar(artifact) {
+		outputDir, err := javaOutputDir()
+		if err != nil {
+			return nil, fmt.Errorf("error creating output directory: %w", err)
+		}
+		dest, err := generateStubs(artifact, outputDir)
+		if err != nil {
+			return nil, fmt.Errorf("error generating stubs: %w", err)
+		}
+		paths, err = listFiles(dest)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		paths, err = common.ListArchive(root.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Ignore non-code files
+	acc := make([]string, 0, len(paths))
+	for _, path := range paths {
+		fileName := filepath.Base(path)
+		if strings.HasSuffix(fileName, ".java") &&
+			fileName != "package-info.java" && fileName != "module-info.java" {
+			acc = append(acc, path)
+		}
+	}
+	return acc, nil
+}
+
+func (Indexer) Parse(root *common.Root, path string, code []byte) ([]*common.SearchDocument, error) {
+	symbols, err := parseJavaSymbols(path, string(code))
 	if err != nil {
-		return fmt.Errorf("error indexing class files: %w", err)
+		return nil, err
 	}
-	slog.Info("Java indexing complete")
-	return nil
+	if len(symbols) == 0 {
+		slog.Debug("No symbols found", "path", path)
+		return nil, nil
+	}
+	documents := make([]*common.SearchDocument, 0, len(symbols))
+	for _, symbol := range symbols {
+		documents = append(documents, &common.SearchDocument{
+			Language: Language,
+			Kind:     symbol.Kind,
+			Name:     symbol.Name,
+			Path:     path,
+			Line:     symbol.Line,
+			Package:  root.Package,
+			Version:  root.Version,
+			Root:     root.Path,
+		})
+	}
+	return documents, nil
+}
+
+// repository is a local artifact cache, which has its own directory layout.
+type repository struct {
+	Path      string
+	ParsePath func(path string) (*MavenCoordinates, error)
 }
 
-func listRepos() ([]string, error) {
+func listRepos() ([]repository, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
-	// TODO Add gradle
 	mavenDir := filepath.Join(home, ".m2", "repository")
-	return []string{mavenDir}, nil
+	gradleHome := os.Getenv("GRADLE_USER_HOME")
+	if gradleHome == "" {
+		gradleHome = filepath.Join(home, ".gradle")
+	}
+	gradleDir := filepath.Join(gradleHome, "caches", "modules-2", "files-2.1")
+	return []repository{
+		{Path: mavenDir, ParsePath: parsePath},
+		{Path: gradleDir, ParsePath: parseGradlePath},
+	}, nil
+}
+
+// parseArtifactPath parses the coordinates of an artifact in any repository.
+func parseArtifactPath(repos []repository, path string) (*MavenCoordinates, error) {
+	for _, repo := range repos {
+		if strings.HasPrefix(path, repo.Path+string(filepath.Separator)) {
+			return repo.ParsePath(path)
+		}
+	}
+	return nil, fmt.Errorf("artifact is not in a repository: %s", path)
 }
 
 type MavenCoordinates struct {
@@ -77,16 +180,30 @@ type MavenCoordinates struct {
 	Classifier string
 }
 
+// Package names the artifact as group:artifact (e.g. com.google.guava:guava).
+func (m *MavenCoordinates) Package() string {
+	return m.GroupId + ":" + m.ArtifactId
+}
+
 func (m *MavenCoordinates) OutputDir() string {
+	// Binary jars (which have no classifier) are indexed from generated stubs
+	classifier := m.Classifier
+	if classifier == "" {
+		classifier = "stubs"
+	}
 	return filepath.Join(
-		m.Classifier,
+		classifier,
 		strings.ReplaceAll(m.GroupId, ".", string(filepath.Separator)),
 		m.ArtifactId, m.Version)
 }
 
-func shouldExtract(path string) bool {
-	return strings.HasSuffix(path, "-javadoc.jar") ||
-		strings.HasSuffix(path, "-sources.jar")
+func isSourcesJar(path string) bool {
+	return strings.HasSuffix(path, "-sources.jar")
+}
+
+// isBinaryJar reports whether an artifact is the jar of compiled classes.
+func isBinaryJar(coords *MavenCoordinates) bool {
+	return coords.Classifier == "" && strings.HasSuffix(coords.Path, ".jar")
 }
 
 func parseClassifier(path string, artifactId string, version string) string {
@@ -119,18 +236,48 @@ func parsePath(path string) (*MavenCoordinates, error) {
 	return nil, fmt.Errorf("invalid Maven path: %s", path)
 }
 
-func discoverArtifacts(inputDir string) ([]*MavenCoordinates, error) {
+// parseGradlePath parses a path in the Gradle cache, which is laid out as
+// files-2.1/<group>/<artifact>/<version>/<hash>/<file>.
+func parseGradlePath(path string) (*MavenCoordinates, error) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == "files-2.1" && i+5 == len(parts)-1 {
+			groupId := parts[i+1]
+			artifactId := parts[i+2]
+			version := parts[i+3]
+			classifier := parseClassifier(path, artifactId, version)
+			return &MavenCoordinates{
+				Path:       path,
+				GroupId:    groupId,
+				ArtifactId: artifactId,
+				Version:    version,
+				Classifier: classifier,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid Gradle path: %s", path)
+}
+
+func discoverArtifacts(repo repository) ([]*MavenCoordinates, error) {
 	acc := []*MavenCoordinates{}
-	err := filepath.WalkDir(inputDir, func(path string, d os.DirEntry, err error) error {
+	err := filepath.WalkDir(repo.Path, func(path string, d os.DirEntry, err error) error {
+		// Log errors (usually permissions) and skip what can't be read
 		if err != nil {
-			return err
+			slog.Warn("Error walking directory", "path", path, "error", err)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
 		}
-		if !d.IsDir() && shouldExtract(path) {
-			coords, err := parsePath(path)
+		if !d.IsDir() && strings.HasSuffix(path, ".jar") {
+			coords, err := repo.ParsePath(path)
 			if err != nil {
-				return err
+				slog.Warn("Skipping jar outside the repository layout", "path", path, "error", err)
+				return nil
+			}
+			if isSourcesJar(path) || isBinaryJar(coords) {
+				acc = append(acc, coords)
 			}
-			acc = append(acc, coords)
 		}
 		return nil
 	})
@@ -140,101 +287,105 @@ func discoverArtifacts(inputDir string) ([]*MavenCoordinates, error) {
 	return acc, nil
 }
 
-func extractZipFile(src string, dst string) error {
-	// Open the archive
-	r, err := zip.OpenReader(src)
+func javaOutputDir() (string, error) {
+	baseOutputDir, err := common.EnsureOutputDir()
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer r.Close()
-	// Extract files
-	for _, f := range r.File {
-		path := filepath.Join(dst, f.Name)
-		// Create archive directory
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(path, 0o755); err != nil {
-				return err
-			}
-			continue
-		}
-		// Create file's parent directory
-		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-			return err
-		}
-		// Create file
-		outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	dir := filepath.Join(baseOutputDir, "java")
+	os.MkdirAll(dir, os.ModePerm)
+	return dir, nil
+}
+
+// findJavaArtifacts returns the artifacts to index: sources jars, and binary
+// jars that were published without sources.
+func findJavaArtifacts() ([]*MavenCoordinates, error) {
+	repos, err := listRepos()
+	if err != nil {
+		return nil, fmt.Errorf("error listing repositories: %w", err)
+	}
+	outputDir, err := javaOutputDir()
+	if err != nil {
+		return nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+	// Older versions extracted every sources and javadoc jar here
+	for _, dir := range []string{"sources", "javadoc"} {
+		err = os.RemoveAll(filepath.Join(outputDir, dir))
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("error removing extracted sources: %w", err)
 		}
-		// Copy file content
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return err
+	}
+	// Discover the artifacts in every repository
+	artifacts := make([]*MavenCoordinates, 0)
+	for _, repo := range repos {
+		if !common.Exists(repo.Path) {
+			slog.Debug("Skipping missing repo", "repo", repo.Path)
+			continue
 		}
-		_, err = io.Copy(outFile, rc)
-		// Cleanup
-		outFile.Close()
-		rc.Close()
+		slog.Debug("Indexing repo", "repo", repo.Path)
+		repoArtifacts, err := discoverArtifacts(repo)
 		if err != nil {
-			return err
+			slog.Error("Error discovering artifacts", "repo", repo.Path, "error", err)
+			return nil, err
 		}
+		artifacts = append(artifacts, repoArtifacts...)
 	}
-	return nil
-}
-
-func extractArtifact(coords *MavenCoordinates, outputDir string) error {
-	slog.Debug("Extracting artifact", "artifact", coords.Path)
-	// Create output directory
-	dest := filepath.Join(outputDir, coords.OutputDir())
-	if _, err := os.Stat(dest); err == nil {
-		return nil
-	}
-	err := os.MkdirAll(dest, 0o755)
-	if err != nil {
-		return err
+	// Find the artifacts that have sources (possibly in another repository)
+	withSources := make(map[string]struct{})
+	for _, artifact := range artifacts {
+		if artifact.Classifier == "sources" {
+			withSources[artifact.Package()+":"+artifact.Version] = struct{}{}
+		}
 	}
-	// Extract the artifact
-	err = extractZipFile(coords.Path, dest)
-	if err != nil {
-		return err
+	acc := make([]*MavenCoordinates, 0)
+	outputDirs := make(map[string]struct{})
+	for _, artifact := range artifacts {
+		// Binary jars are only used when no sources were published
+		_, hasSources := withSources[artifact.Package()+":"+artifact.Version]
+		if isBinaryJar(artifact) && hasSources {
+			continue
+		}
+		// Artifacts in both Maven and Gradle caches are only indexed once
+		if _, ok := outputDirs[artifact.OutputDir()]; ok {
+			continue
+		}
+		outputDirs[artifact.OutputDir()] = struct{}{}
+		acc = append(acc, artifact)
 	}
-	return nil
+	return acc, nil
 }
 
-func javaOutputDir() (string, error) {
-	baseOutputDir, err := common.EnsureOutputDir()
+// RemoveRoots removes the generated stubs of artifacts that were deleted from
+// the repositories (e.g. after a version upgrade).
+func (Indexer) RemoveRoots(stale []string, current []*common.Root) error {
+	outputDir, err := javaOutputDir()
 	if err != nil {
-		return "", err
+		return err
 	}
-	dir := filepath.Join(baseOutputDir, "java")
-	os.MkdirAll(dir, os.ModePerm)
-	return dir, nil
-}
-
-func processJavaArtifacts() error {
 	repos, err := listRepos()
 	if err != nil {
-		return fmt.Errorf("error listing repositories: %w", err)
+		return err
 	}
-	outputDir, err := javaOutputDir()
-	if err != nil {
-		return fmt.Errorf("error creating output directory: %w", err)
+	// Keep output directories shared with a current artifact
+	currentDirs := make(map[string]struct{})
+	for _, root := range current {
+		if artifact, ok := root.Source.(*MavenCoordinates); ok {
+			currentDirs[artifact.OutputDir()] = struct{}{}
+		}
 	}
-	for _, repo := range repos {
-		slog.Debug("Indexing repo", "repo", repo)
-		artifacts, err := discoverArtifacts(repo)
+	for _, root := range stale {
+		// Only binary jars have an output directory (sources are read in place)
+		coords, err := parseArtifactPath(repos, root)
+		if err != nil || !isBinaryJar(coords) {
+			continue
+		}
+		if _, ok := currentDirs[coords.OutputDir()]; ok {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(outputDir, coords.OutputDir()))
 		if err != nil {
-			slog.Error("Error discovering artifacts", "repo", repo, "error", err)
 			return err
 		}
-		for _, artifact := range artifacts {
-			err = extractArtifact(artifact, outputDir)
-			if err != nil {
-				slog.Error("Error extracting artifact", "artifact", artifact, "error", err)
-				return err
-			}
-		}
 	}
 	return nil
 }
@@ -275,75 +426,50 @@ func findJDKSourceArchive(version string) (string, error) {
 	return "", fmt.Errorf("JDK source archive not found for version %s", version)
 }
 
-func extractJDKSourceArchive(archive string) error {
-	outputDir, err := javaOutputDir()
-	if err != nil {
-		return fmt.Errorf("error creating output directory: %w", err)
-	}
-	jdkOutputDir := filepath.Join(outputDir, "sources", "jdk")
-	err = os.MkdirAll(jdkOutputDir, 0o755)
-	if err != nil {
-		return fmt.Errorf("error creating JDK output directory: %w", err)
-	}
-	err = extractZipFile(archive, jdkOutputDir)
-	if err != nil {
-		return fmt.Errorf("error extracting JDK source archive: %w", err)
-	}
-	slog.Debug("Extracted JDK source archive", "archive", archive, "outputDir", jdkOutputDir)
-	return nil
-}
-
-func processJDKClasses() error {
-	// Find the JDK version
-	version, err := findJavaVersion()
-	if err != nil {
-		return fmt.Errorf("error finding Java version: %w", err)
-	}
-	// Find the JDK source archive
-	archive, err := findJDKSourceArchive(version)
-	if err != nil {
-		return fmt.Errorf("error finding JDK source archive: %w", err)
-	}
-	// Extract the JDK source archive
-	err = extractJDKSourceArchive(archive)
-	if err != nil {
-		return fmt.Errorf("error extracting JDK source archive: %w", err)
-	}
-	return nil
-}
-
 // TODO Use tree sitter instead to find classes? https://github.com/tree-sitter/go-tree-sitter
 var (
 	packageNameRegex = regexp.MustCompile(
 		`(?m)package\s+([a-zA-Z0-9_.]+);`)
-	classNameRegex = regexp.MustCompile(`(?:class|interface|record|enum)\s+([a-zA-Z0-9_$]+)`)
-	jdkPathPart    = string(filepath.Separator) + "jdk" + string(filepath.Separator)
-	spaceRegex     = regexp.MustCompile(`\s+`) // Matches one or more whitespace characters
+	classNameRegex = regexp.MustCompile(`\b(class|interface|record|enum)\s+([a-zA-Z0-9_$]+)`)
+	methodRegex    = regexp.MustCompile(
+		`^(?:@[\w.]+(?:\([^)]*\))?\s+)*` + // annotations
+			`((?:(?:public|protected|private|static|final|abstract|synchronized|native|default|strictfp)\s+)*)` +
+			`(?:<[^>]*>\s+)?` + // type parameters
+			`((?:[\w$.]+(?:<[^;=(]*>)?(?:\[\])*\s+)?)` + // return type
+			`([a-zA-Z_$][\w$]*)\s*\(`)
+	constantRegex = regexp.MustCompile(
+		`^(?:@[\w.]+(?:\([^)]*\))?\s+)*` +
+			`((?:(?:public|protected|private|static|final|transient|volatile)\s+)+)` +
+			`[\w$.]+(?:<[^;=]*>)?(?:\[\])*\s+([A-Z][A-Z0-9_]*)\s*[=;]`)
+	javaKinds = map[string]common.Kind{
+		"class":     common.Class,
+		"interface": common.Interface,
+		"record":    common.Record,
+		"enum":      common.Enum,
+	}
+	javaKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "catch": true, "synchronized": true,
+		"return": true, "new": true, "throw": true, "else": true, "try": true, "do": true,
+	}
 )
 
-func parseJdkPackageName(path string) (string, error) {
-	dir, _ := filepath.Split(path)
-	parts := strings.Split(dir, string(filepath.Separator))
-	acc := make([]string, 0)
-	collect := false
-	i := 0
-	for i < len(parts) {
-		if parts[i] == "jdk" {
-			i += 2
-			collect = true
-			continue
-		}
-		if collect && len(parts[i]) > 0 {
-			acc = append(acc, parts[i])
-		}
-		i++
-	}
-	return strings.Join(acc, "."), nil
+// parseJdkPackageName parses the package of an entry in the JDK's src.zip,
+// which is laid out as <module>/<package>/<Class>.java (or without the module
+// before Java 9).
+func parseJdkPackageName(entry string) (string, error) {
+	parts := strings.Split(path.Dir(entry), "/")
+	if len(parts) > 0 && strings.Contains(parts[0], ".") {
+		parts = parts[1:] // e.g. java.base
+	}
+	if len(parts) == 0 || parts[0] == "." {
+		return "", fmt.Errorf("no package name found in %s", entry)
+	}
+	return strings.Join(parts, "."), nil
 }
 
 func parseJavaPackageName(path, code string) (string, error) {
-	if strings.Contains(path, jdkPathPart) {
-		return parseJdkPackageName(path)
+	if archive, entry, ok := common.SplitArchivePath(path); ok && filepath.Base(archive) == "src.zip" {
+		return parseJdkPackageName(entry)
 	}
 	packageNameMatch := packageNameRegex.FindStringSubmatch(code)
 	if len(packageNameMatch) < 2 {
@@ -352,96 +478,136 @@ func parseJavaPackageName(path, code string) (string, error) {
 	return packageNameMatch[1], nil
 }
 
-func parseJavaClassNames(path, code string) ([]string, error) {
+// stripJavaLine removes comments and the contents of string and character
+// literals, so that braces and keywords inside them are ignored.
+func stripJavaLine(line string, inComment *bool) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if *inComment {
+			if strings.HasPrefix(line[i:], "*/") {
+				*inComment = false
+				i++
+			}
+			continue
+		}
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "//"):
+			return b.String()
+		case strings.HasPrefix(line[i:], "/*"):
+			*inComment = true
+			i++
+		case c == '"' || c == '\'':
+			// Keep the quotes but drop the literal
+			b.WriteByte(c)
+			for i++; i < len(line) && line[i] != c; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// javaScope is a class body, which opens at the given brace depth.
+type javaScope struct {
+	name   string
+	depth  int
+	opened bool
+}
+
+func parseJavaSymbols(path, code string) ([]common.Symbol, error) {
 	// Parse the package
 	packageName, err := parseJavaPackageName(path, code)
 	if err != nil {
 		return nil, err
 	}
-	// Parse the file name (to determine the top-level class name)
-	topLevelClassName := filepath.Base(path)
-	topLevelClassName = strings.TrimSuffix(topLevelClassName, filepath.Ext(topLevelClassName))
-	// Parse all class names
-	classNames := make([]string, 0)
-	for line := range strings.SplitSeq(code, "\n") {
+	symbols := make([]common.Symbol, 0)
+	scopes := make([]*javaScope, 0)
+	depth := 0
+	inComment := false
+	for i, line := range strings.Split(code, "\n") {
 		// Clean up the line
-		line = strings.ReplaceAll(line, "/* package */", "")
-		line = strings.TrimSpace(line)
-		// Ignore empty lines and lines with comments
-		tokens := spaceRegex.Split(line, -1)
-		if len(tokens) == 0 || tokens[0] == "//" || tokens[0] == "/*" || tokens[0] == "*" || tokens[0] == "*/" {
+		line = strings.TrimSpace(stripJavaLine(line, &inComment))
+		if line == "" {
 			continue
 		}
-		// Find symbols prefixed by class/interface/record/enum
-		classNameMatches := classNameRegex.FindAllStringSubmatch(line, -1)
-		for _, match := range classNameMatches {
-			if len(match) < 2 {
-				continue
+		var scope *javaScope
+		if len(scopes) > 0 {
+			scope = scopes[len(scopes)-1]
+		}
+		if match := classNameRegex.FindStringSubmatch(line); match != nil {
+			// Classes are named after their enclosing classes (e.g. Map.Entry)
+			className := match[2]
+			if scope != nil {
+				className = scope.name + "." + className
+			}
+			symbols = append(symbols, common.Symbol{
+				Name: fmt.Sprintf("%s.%s", packageName, className),
+				Kind: javaKinds[match[1]],
+				Line: i + 1,
+			})
+			scopes = append(scopes, &javaScope{name: className, depth: depth + 1})
+		} else if scope != nil && scope.opened && depth == scope.depth {
+			// Members declared directly in the class body
+			if match := constantRegex.FindStringSubmatch(line); match != nil {
+				if strings.Contains(match[1], "static") && strings.Contains(match[1], "final") {
+					symbols = append(symbols, common.Symbol{
+						Name: fmt.Sprintf("%s.%s.%s", packageName, scope.name, match[2]),
+						Kind: common.Constant,
+						Line: i + 1,
+					})
+				}
+			} else if match := methodRegex.FindStringSubmatch(line); match != nil {
+				modifiers, returnType, name := match[1], strings.TrimSpace(match[2]), match[3]
+				isConstructor := strings.HasSuffix("."+scope.name, "."+name)
+				if !javaKeywords[name] && !javaKeywords[returnType] &&
+					(modifiers != "" || returnType != "" || isConstructor) {
+					symbols = append(symbols, common.Symbol{
+						Name: fmt.Sprintf("%s.%s.%s", packageName, scope.name, name),
+						Kind: common.Method,
+						Line: i + 1,
+					})
+				}
 			}
-			className := match[1]
-			if className != topLevelClassName {
-				// If different from the top-level class name, it's an inner class
-				className = fmt.Sprintf("%s.%s", topLevelClassName, className)
+		}
+		// Track the class bodies that are currently open
+		for _, c := range line {
+			switch c {
+			case '{':
+				depth++
+				if len(scopes) > 0 && !scopes[len(scopes)-1].opened && scopes[len(scopes)-1].depth == depth {
+					scopes[len(scopes)-1].opened = true
+				}
+			case '}':
+				depth--
+				for len(scopes) > 0 && scopes[len(scopes)-1].opened && depth < scopes[len(scopes)-1].depth {
+					scopes = scopes[:len(scopes)-1]
+				}
 			}
-			// Construct fully qualified class name
-			fullClassName := fmt.Sprintf("%s.%s", packageName, className)
-			classNames = append(classNames, fullClassName)
 		}
 	}
-	return classNames, nil
+	return symbols, nil
 }
 
-func indexClassFiles(db *sql.DB) error {
-	// Create the application data directory if it doesn't exist
-	outputDir, err := javaOutputDir()
-	if err != nil {
-		return fmt.Errorf("error creating output directory: %w", err)
-	}
-	// Find all Java class files
-	sourceDir := filepath.Join(outputDir, "sources")
-	documents := make([]*common.SearchDocument, 0)
-	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
-		// Ignore non-code files
-		fileName := filepath.Base(path)
-		if fileName == "package-info.java" || fileName == "module-info.java" {
-			return nil
+// listFiles returns the files in a directory of generated stubs.
+func listFiles(dir string) ([]string, error) {
+	acc := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
-		// Process Java files
-		if !info.IsDir() && strings.HasSuffix(path, ".java") {
-			data, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			code := string(data)
-			names, err := parseJavaClassNames(path, code)
-			if err != nil {
-				slog.Error("Error parsing Java class name", "path", path, "error", err)
-				return nil
-			}
-			if len(names) == 0 {
-				slog.Info("No class names found", "path", path)
-				return nil
-			}
-			for _, name := range names {
-				document := &common.SearchDocument{
-					Language: common.Java,
-					Name:     name,
-					Path:     path,
-				}
-				slog.Debug("Found Java class", "name", name, "path", path)
-				documents = append(documents, document)
-			}
+		if !d.IsDir() {
+			acc = append(acc, path)
 		}
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("error walking the path %v: %w", sourceDir, err)
-	}
-	slog.Info("Found Java class files", "count", len(documents))
-	// Index the documents
-	err = common.IndexDocuments(db, documents)
-	if err != nil {
-		return fmt.Errorf("error indexing documents: %w", err)
+		return nil, fmt.Errorf("error walking the path %v: %w", dir, err)
 	}
-	return nil
+	return acc, nil
 }
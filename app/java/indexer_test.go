@@ -1,18 +1,23 @@
 package java
 
 import (
-	"slices"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
 )
 
-func TestParseJavaClassNames(t *testing.T) {
+func TestParseJavaSymbols(t *testing.T) {
 	// Parse a class where the class/enum keywords are used in comments
-	classNames, err := parseJavaClassNames(
+	symbols, err := parseJavaSymbols(
 		"com/abc/def/xyz/HelloWorld.java",
 		`
 		package com.abc.def.xyz;
 
 		public class HelloWorld {
+			private static final int MAX_SIZE = 10;
+
 			/**
 			 * This is a class that prints "Hello, World!" to the console.
 			 */
@@ -22,9 +27,13 @@ func TestParseJavaClassNames(t *testing.T) {
 
 			// This is an enum which is used to represent colors.
 			public static enum Color {
-				RED,
-				GREEN,
-				BLUE
+				RED("r"),
+				GREEN("g"),
+				BLUE("b");
+
+				Color(String code) {
+					this.code = "class Foo {";
+				}
 			}
 
 			public static abstract class SomeAbstractClass {
@@ -34,18 +43,78 @@ func TestParseJavaClassNames(t *testing.T) {
 		}
 		`)
 	if err != nil {
-		t.Errorf("ParseJavaClassNames failed: %v", err)
+		t.Errorf("ParseJavaSymbols failed: %v", err)
 	}
-	if len(classNames) != 3 {
-		t.Errorf("ParseJavaClassNames returned unexpected number of class names: %d", len(classNames))
+	expected := []common.Symbol{
+		{Name: "com.abc.def.xyz.HelloWorld", Kind: common.Class, Line: 4},
+		{Name: "com.abc.def.xyz.HelloWorld.MAX_SIZE", Kind: common.Constant, Line: 5},
+		{Name: "com.abc.def.xyz.HelloWorld.main", Kind: common.Method, Line: 10},
+		{Name: "com.abc.def.xyz.HelloWorld.Color", Kind: common.Enum, Line: 15},
+		{Name: "com.abc.def.xyz.HelloWorld.Color.Color", Kind: common.Method, Line: 20},
+		{Name: "com.abc.def.xyz.HelloWorld.SomeAbstractClass", Kind: common.Class, Line: 25},
+		{Name: "com.abc.def.xyz.HelloWorld.SomeAbstractClass.doSomething", Kind: common.Method, Line: 27},
 	}
-	if !slices.Contains(classNames, "com.abc.def.xyz.HelloWorld") {
-		t.Errorf("missing top-level class name: com.abc.def.xyz.HelloWorld")
+	if len(symbols) != len(expected) {
+		t.Fatalf("ParseJavaSymbols returned unexpected number of symbols: %v", symbols)
 	}
-	if !slices.Contains(classNames, "com.abc.def.xyz.HelloWorld.Color") {
-		t.Errorf("missing inner class name: com.abc.def.xyz.HelloWorld.Color")
+	for i, symbol := range symbols {
+		if symbol != expected[i] {
+			t.Errorf("unexpected symbol: got %v, expected %v", symbol, expected[i])
+		}
 	}
-	if !slices.Contains(classNames, "com.abc.def.xyz.HelloWorld.SomeAbstractClass") {
-		t.Errorf("missing abstract class name: com.abc.def.xyz.HelloWorld.SomeAbstractClass")
+}
+
+func TestParseGradlePath(t *testing.T) {
+	coords, err := parseGradlePath(
+		"/home/user/.gradle/caches/modules-2/files-2.1/com.google.guava/guava/31.1-jre/" +
+			"0b6e0ae2c9a2b7a4d6a6f8a2c5e9a8c5f1d2e3f4/guava-31.1-jre-sources.jar")
+	if err != nil {
+		t.Fatalf("parseGradlePath failed: %v", err)
+	}
+	if coords.GroupId != "com.google.guava" || coords.ArtifactId != "guava" ||
+		coords.Version != "31.1-jre" || coords.Classifier != "sources" {
+		t.Errorf("parseGradlePath returned unexpected coordinates: %+v", coords)
+	}
+	_, err = parseGradlePath("/home/user/.gradle/caches/modules-2/files-2.1/com.google.guava/guava.jar")
+	if err == nil {
+		t.Errorf("parseGradlePath accepted a path outside of the cache layout")
+	}
+}
+
+func TestDiscoverArtifactsSkipsUnknownJars(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "files-2.1")
+	jars := []string{
+		"com.google.guava/guava/31.1-jre/0b6e0ae2/guava-31.1-jre-sources.jar",
+		// Outside of the cache layout
+		"com.google.guava/guava/stray.jar",
+	}
+	for _, jar := range jars {
+		path := filepath.Join(cache, filepath.FromSlash(jar))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, nil, 0o644)
+	}
+	artifacts, err := discoverArtifacts(repository{Path: cache, ParsePath: parseGradlePath})
+	if err != nil {
+		t.Fatalf("discoverArtifacts failed: %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].ArtifactId != "guava" {
+		t.Errorf("unexpected artifacts: %+v", artifacts)
+	}
+	// A missing repository has no artifacts
+	artifacts, err = discoverArtifacts(repository{Path: filepath.Join(cache, "missing"), ParsePath: parseGradlePath})
+	if err != nil || len(artifacts) != 0 {
+		t.Errorf("unexpected artifacts in a missing repository: %+v (%v)", artifacts, err)
+	}
+}
+
+func TestParseJdkPackageName(t *testing.T) {
+	for entry, expected := range map[string]string{
+		"java.base/java/util/concurrent/ConcurrentHashMap.java": "java.util.concurrent",
+		"java/lang/String.java":                                 "java.lang",
+	} {
+		packageName, err := parseJdkPackageName(entry)
+		if err != nil || packageName != expected {
+			t.Errorf("parseJdkPackageName(%q) = %q (%v), expected %q", entry, packageName, err, expected)
+		}
 	}
 }
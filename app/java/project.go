@@ -0,0 +1,279 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Dependencies reads the artifacts a project uses from its Gradle lockfiles,
+// or the dependencies declared in its pom.xml files. Transitive dependencies of
+// Maven projects are only included if they were resolved to
+// target/dependency-list.txt (see parseDependencyList).
+func (Indexer) Dependencies(projectDir string) ([]common.Dependency, error) {
+	acc, err := findGradleLockfileDependencies(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	if common.Exists(filepath.Join(projectDir, "pom.xml")) {
+		pomDependencies, err := findPomDependencies(projectDir, map[string]string{}, map[string]string{}, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		acc = append(acc, pomDependencies...)
+	}
+	return acc, nil
+}
+
+// findGradleLockfileDependencies reads gradle.lockfile (or the older
+// gradle/dependency-locks/*.lockfile) of each Gradle project.
+func findGradleLockfileDependencies(projectDir string) ([]common.Dependency, error) {
+	acc := make([]common.Dependency, 0)
+	err := filepath.WalkDir(projectDir, func(path string, d fs.DirEntry, err error) error {
+		// Log errors (usually permissions) and skip what can't be read
+		if err != nil {
+			slog.Warn("Error walking directory", "path", path, "error", err)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() && path != projectDir &&
+			(strings.HasPrefix(d.Name(), ".") || d.Name() == "build" || d.Name() == "node_modules") {
+			return fs.SkipDir
+		}
+		isLockfile := d.Name() == "gradle.lockfile" ||
+			(strings.HasSuffix(d.Name(), ".lockfile") && filepath.Base(filepath.Dir(path)) == "dependency-locks")
+		if d.IsDir() || !isLockfile {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		acc = append(acc, parseGradleLockfile(file)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// parseGradleLockfile parses lines like "group:artifact:version=configurations".
+func parseGradleLockfile(r io.Reader) []common.Dependency {
+	acc := make([]common.Dependency, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		coords, _, _ := strings.Cut(line, "=")
+		parts := strings.Split(coords, ":")
+		if len(parts) != 3 {
+			continue
+		}
+		acc = append(acc, common.Dependency{
+			Language: Language,
+			Package:  parts[0] + ":" + parts[1],
+			Version:  parts[2],
+		})
+	}
+	return acc
+}
+
+type pomDependency struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+// pomProperties collects the elements of <properties>.
+type pomProperties map[string]string
+
+func (p *pomProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = make(pomProperties)
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &token); err != nil {
+				return err
+			}
+			(*p)[token.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type pom struct {
+	GroupId string `xml:"groupId"`
+	Version string `xml:"version"`
+	Parent  struct {
+		GroupId string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Properties   pomProperties   `xml:"properties"`
+	Dependencies []pomDependency `xml:"dependencies>dependency"`
+	Managed      []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Modules      []string        `xml:"modules>module"`
+}
+
+var pomPropertyRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// resolvePomValue substitutes ${...} properties, or returns "" if the value
+// is a version range or refers to an unknown property.
+func resolvePomValue(value string, properties map[string]string) string {
+	for range 10 {
+		if !strings.Contains(value, "${") {
+			break
+		}
+		value = pomPropertyRegex.ReplaceAllStringFunc(value, func(match string) string {
+			return properties[match[2:len(match)-1]]
+		})
+	}
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "${") || strings.ContainsAny(value, "[(,") {
+		return ""
+	}
+	return value
+}
+
+// dependencyListFile is where `mvn dependency:list
+// -DoutputFile=target/dependency-list.txt` writes the resolved dependencies of
+// each module.
+const dependencyListFile = "target/dependency-list.txt"
+
+// parseDependencyList parses the output of mvn dependency:list, with lines like
+// "group:artifact:type[:classifier]:version:scope".
+func parseDependencyList(r io.Reader) []common.Dependency {
+	acc := make([]common.Dependency, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		parts := strings.Split(fields[0], ":")
+		if len(parts) != 5 && len(parts) != 6 {
+			continue
+		}
+		acc = append(acc, common.Dependency{
+			Language: Language,
+			Package:  parts[0] + ":" + parts[1],
+			Version:  parts[len(parts)-2],
+		})
+	}
+	return acc
+}
+
+// findPomDependencies reads the dependencies in the pom.xml of a Maven project
+// and its modules, which inherit its properties and managed versions, and the
+// resolved dependencies in their dependency lists.
+func findPomDependencies(
+	dir string,
+	inherited map[string]string,
+	inheritedManaged map[string]string,
+	visited map[string]bool,
+) ([]common.Dependency, error) {
+	if visited[dir] {
+		return nil, nil
+	}
+	visited[dir] = true
+	data, err := os.ReadFile(filepath.Join(dir, "pom.xml"))
+	if err != nil {
+		return nil, err
+	}
+	var project pom
+	err = xml.Unmarshal(data, &project)
+	if err != nil {
+		return nil, err
+	}
+	// Properties of the parent, then this project
+	properties := make(map[string]string)
+	for key, value := range inherited {
+		properties[key] = value
+	}
+	for key, value := range project.Properties {
+		properties[key] = value
+	}
+	groupId, version := project.GroupId, project.Version
+	if groupId == "" {
+		groupId = project.Parent.GroupId
+	}
+	if version == "" {
+		version = project.Parent.Version
+	}
+	properties["project.groupId"] = groupId
+	properties["project.version"] = version
+	properties["project.parent.version"] = project.Parent.Version
+	// Versions of dependencies that don't declare one, from the parent, then
+	// this project
+	managed := make(map[string]string)
+	for key, value := range inheritedManaged {
+		managed[key] = value
+	}
+	for _, dependency := range project.Managed {
+		pkg := resolvePomValue(dependency.GroupId, properties) + ":" + resolvePomValue(dependency.ArtifactId, properties)
+		managed[pkg] = resolvePomValue(dependency.Version, properties)
+	}
+	acc := make([]common.Dependency, 0)
+	for _, dependency := range project.Dependencies {
+		pkg := resolvePomValue(dependency.GroupId, properties) + ":" + resolvePomValue(dependency.ArtifactId, properties)
+		version := resolvePomValue(dependency.Version, properties)
+		if dependency.Version == "" {
+			version = managed[pkg]
+		}
+		acc = append(acc, common.Dependency{
+			Language: Language,
+			Package:  pkg,
+			Version:  version,
+		})
+	}
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(dependencyListFile)))
+	if err == nil {
+		acc = append(acc, parseDependencyList(file)...)
+		file.Close()
+	}
+	for _, module := range project.Modules {
+		moduleDir := filepath.Join(dir, filepath.FromSlash(module))
+		if !common.Exists(filepath.Join(moduleDir, "pom.xml")) {
+			continue
+		}
+		moduleDependencies, err := findPomDependencies(moduleDir, properties, managed, visited)
+		if err != nil {
+			return nil, err
+		}
+		acc = append(acc, moduleDependencies...)
+	}
+	return acc, nil
+}
@@ -0,0 +1,117 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func formatDependencies(dependencies []common.Dependency) []string {
+	acc := make([]string, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		acc = append(acc, dependency.Package+"@"+dependency.Version)
+	}
+	slices.Sort(acc)
+	return acc
+}
+
+func TestParseGradleLockfile(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"com.google.guava:guava:33.0.0-jre=compileClasspath,runtimeClasspath", []string{"com.google.guava:guava@33.0.0-jre"}},
+		{"org.slf4j:slf4j-api:2.0.9=runtimeClasspath", []string{"org.slf4j:slf4j-api@2.0.9"}},
+		{"# This is a Gradle generated file for dependency locking.", []string{}},
+		{"empty=annotationProcessor", []string{}},
+	}
+	for _, test := range tests {
+		actual := formatDependencies(parseGradleLockfile(strings.NewReader(test.line)))
+		if !slices.Equal(actual, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.line, test.expected, actual)
+		}
+	}
+}
+
+func TestParseDependencyList(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"   org.slf4j:slf4j-api:jar:2.0.9:compile", []string{"org.slf4j:slf4j-api@2.0.9"}},
+		{"   com.google.guava:guava:jar:33.0.0-jre:compile -- module com.google.common", []string{"com.google.guava:guava@33.0.0-jre"}},
+		{"   io.netty:netty-transport-native-epoll:jar:linux-x86_64:4.1.100.Final:runtime", []string{"io.netty:netty-transport-native-epoll@4.1.100.Final"}},
+		{"The following files have been resolved:", []string{}},
+		{"   none", []string{}},
+	}
+	for _, test := range tests {
+		actual := formatDependencies(parseDependencyList(strings.NewReader(test.line)))
+		if !slices.Equal(actual, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.line, test.expected, actual)
+		}
+	}
+}
+
+func TestFindPomDependencies(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pom.xml": `<project>
+  <groupId>com.example</groupId>
+  <version>1.0</version>
+  <properties>
+    <guava.version>33.0.0-jre</guava.version>
+  </properties>
+  <modules><module>core</module><module>missing</module></modules>
+  <dependencyManagement>
+    <dependencies>
+      <dependency><groupId>com.google.guava</groupId><artifactId>guava</artifactId><version>${guava.version}</version></dependency>
+      <dependency><groupId>${project.groupId}</groupId><artifactId>api</artifactId><version>${project.version}</version></dependency>
+    </dependencies>
+  </dependencyManagement>
+  <dependencies>
+    <dependency><groupId>junit</groupId><artifactId>junit</artifactId><version>[4.0,5.0)</version></dependency>
+  </dependencies>
+</project>`,
+		// The module inherits the properties and managed versions
+		"core/pom.xml": `<project>
+  <parent><groupId>com.example</groupId><version>1.0</version></parent>
+  <properties>
+    <slf4j.version>2.0.9</slf4j.version>
+  </properties>
+  <dependencies>
+    <dependency><groupId>com.google.guava</groupId><artifactId>guava</artifactId></dependency>
+    <dependency><groupId>com.example</groupId><artifactId>api</artifactId></dependency>
+    <dependency><groupId>org.slf4j</groupId><artifactId>slf4j-api</artifactId><version>${slf4j.version}</version></dependency>
+    <dependency><groupId>org.unknown</groupId><artifactId>unknown</artifactId></dependency>
+  </dependencies>
+</project>`,
+		"core/target/dependency-list.txt": `
+The following files have been resolved:
+   com.google.guava:failureaccess:jar:1.0.2:compile -- module failureaccess
+`,
+	}
+	for name, body := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(body), 0o644)
+	}
+	dependencies, err := Indexer{}.Dependencies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"com.example:api@1.0",
+		"com.google.guava:failureaccess@1.0.2",
+		"com.google.guava:guava@33.0.0-jre",
+		"junit:junit@",
+		"org.slf4j:slf4j-api@2.0.9",
+		"org.unknown:unknown@",
+	}
+	if actual := formatDependencies(dependencies); !slices.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
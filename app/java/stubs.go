@@ -0,0 +1,345 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Class files larger than this are skipped when generating stubs
+const maxClassFileSize = 4 << 20
+
+// simpleClassName returns the name of a class without its package or
+// enclosing classes (e.g. java/util/Map$Entry -> Entry).
+func simpleClassName(binaryName string) string {
+	name := binaryName[strings.LastIndex(binaryName, "/")+1:]
+	return name[strings.LastIndex(name, "$")+1:]
+}
+
+// isAnonymousClass reports whether a class is anonymous or local (e.g. Foo$1
+// or Foo$1Bar), which is not part of an API.
+func isAnonymousClass(binaryName string) bool {
+	name := binaryName[strings.LastIndex(binaryName, "/")+1:]
+	for _, part := range strings.Split(name, "$")[1:] {
+		if part == "" || (part[0] >= '0' && part[0] <= '9') {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidClassName reports whether a binary class name can be used as a path
+// in the stub directory.
+func isValidClassName(binaryName string) bool {
+	for _, part := range strings.Split(binaryName, "/") {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `\:`) {
+			return false
+		}
+	}
+	return true
+}
+
+func modifiers(flags uint16, allowed uint16) string {
+	names := []struct {
+		flag uint16
+		name string
+	}{
+		{accPublic, "public"},
+		{accProtected, "protected"},
+		{accPrivate, "private"},
+		{accAbstract, "abstract"},
+		{accStatic, "static"},
+		{accFinal, "final"},
+		{accTransient, "transient"},
+		{accVolatile, "volatile"},
+		{0x0020, "synchronized"},
+		{accNative, "native"},
+	}
+	acc := make([]string, 0)
+	for _, n := range names {
+		if flags&allowed&n.flag != 0 {
+			acc = append(acc, n.name)
+		}
+	}
+	if len(acc) == 0 {
+		return ""
+	}
+	return strings.Join(acc, " ") + " "
+}
+
+func writeAnnotations(b *strings.Builder, annotations []string, indent string) {
+	for _, annotation := range annotations {
+		name, _ := parseFieldType(annotation)
+		if name != "" {
+			fmt.Fprintf(b, "%s@%s\n", indent, name)
+		}
+	}
+}
+
+// classKeyword returns the keyword a class is declared with.
+func classKeyword(class *classFile, flags uint16) string {
+	switch {
+	case flags&accAnnotation != 0:
+		return "@interface"
+	case flags&accInterface != 0:
+		return "interface"
+	case flags&accEnum != 0 && class.SuperName == "java/lang/Enum":
+		return "enum"
+	case class.SuperName == "java/lang/Record":
+		return "record"
+	default:
+		return "class"
+	}
+}
+
+func writeMethod(b *strings.Builder, class *classFile, flags uint16, method *classMember, indent string) {
+	params, returnType := parseMethodDescriptor(method.Descriptor)
+	// Inner (non-static) class constructors take the enclosing instance first
+	if method.Name == "<init>" && class.IsInner && flags&accStatic == 0 && len(params) > 0 &&
+		len(method.ParameterNames) != len(params) {
+		params = params[1:]
+	}
+	names := method.ParameterNames
+	if len(names) != len(params) {
+		names = make([]string, len(params))
+	}
+	for i := range params {
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("arg%d", i)
+		}
+		if i == len(params)-1 && method.AccessFlags&accVarargs != 0 {
+			params[i] = strings.TrimSuffix(params[i], "[]") + "..."
+		}
+		params[i] = params[i] + " " + names[i]
+	}
+	writeAnnotations(b, method.Annotations, indent)
+	allowed := uint16(accPublic | accProtected | accAbstract | accStatic | accFinal | 0x0020 | accNative)
+	prefix := ""
+	if flags&accInterface != 0 {
+		// Interface methods are implicitly public, and abstract unless default
+		allowed = accStatic
+		if method.AccessFlags&(accAbstract|accStatic) == 0 {
+			prefix = "default "
+		}
+	}
+	b.WriteString(indent + modifiers(method.AccessFlags, allowed) + prefix)
+	if method.Name == "<init>" {
+		b.WriteString(simpleClassName(class.Name))
+	} else {
+		b.WriteString(returnType + " " + method.Name)
+	}
+	b.WriteString("(" + strings.Join(params, ", ") + ")")
+	if len(method.Exceptions) > 0 {
+		exceptions := make([]string, len(method.Exceptions))
+		for i, exception := range method.Exceptions {
+			exceptions[i] = javaTypeName(exception)
+		}
+		b.WriteString(" throws " + strings.Join(exceptions, ", "))
+	}
+	b.WriteString(";\n")
+}
+
+// writeStub writes the API of a class (and its nested classes) as Java source,
+// without method bodies.
+func writeStub(b *strings.Builder, class *classFile, nested map[string][]*classFile, indent string) {
+	flags := class.AccessFlags
+	if class.IsInner {
+		flags = class.InnerAccessFlags
+	}
+	keyword := classKeyword(class, flags)
+	// Declaration
+	writeAnnotations(b, class.Annotations, indent)
+	allowed := uint16(accPublic | accProtected | accPrivate | accStatic | accAbstract | accFinal)
+	switch keyword {
+	case "@interface", "interface":
+		allowed &^= accAbstract
+	case "enum", "record":
+		allowed &^= accFinal | accAbstract
+	}
+	fmt.Fprintf(b, "%s%s%s %s", indent, modifiers(flags, allowed), keyword, simpleClassName(class.Name))
+	if keyword == "record" {
+		components := make([]string, 0)
+		for _, field := range class.Fields {
+			if field.AccessFlags&accStatic == 0 {
+				fieldType, _ := parseFieldType(field.Descriptor)
+				components = append(components, fieldType+" "+field.Name)
+			}
+		}
+		b.WriteString("(" + strings.Join(components, ", ") + ")")
+	}
+	if keyword == "class" && class.SuperName != "" && class.SuperName != "java/lang/Object" {
+		b.WriteString(" extends " + javaTypeName(class.SuperName))
+	}
+	interfaces := make([]string, 0)
+	for _, iface := range class.Interfaces {
+		if iface != "java/lang/annotation/Annotation" {
+			interfaces = append(interfaces, javaTypeName(iface))
+		}
+	}
+	if len(interfaces) > 0 {
+		if keyword == "interface" {
+			b.WriteString(" extends " + strings.Join(interfaces, ", "))
+		} else {
+			b.WriteString(" implements " + strings.Join(interfaces, ", "))
+		}
+	}
+	b.WriteString(" {\n")
+	memberIndent := indent + "    "
+	// Enum constants
+	if keyword == "enum" {
+		constants := make([]string, 0)
+		for _, field := range class.Fields {
+			if field.AccessFlags&accEnum != 0 {
+				constants = append(constants, memberIndent+field.Name)
+			}
+		}
+		b.WriteString(strings.Join(constants, ",\n") + ";\n")
+	}
+	// Fields
+	for _, field := range class.Fields {
+		if field.AccessFlags&(accPrivate|accSynthetic|accEnum) != 0 {
+			continue
+		}
+		fieldType, _ := parseFieldType(field.Descriptor)
+		writeAnnotations(b, field.Annotations, memberIndent)
+		allowed := uint16(accPublic | accProtected | accStatic | accFinal | accTransient | accVolatile)
+		if flags&accInterface != 0 {
+			allowed = 0
+		}
+		fmt.Fprintf(b, "%s%s%s %s", memberIndent, modifiers(field.AccessFlags, allowed), fieldType, field.Name)
+		if field.ConstantValue != "" {
+			b.WriteString(" = " + field.ConstantValue)
+		}
+		b.WriteString(";\n")
+	}
+	// Methods
+	for _, method := range class.Methods {
+		if method.AccessFlags&(accPrivate|accSynthetic|accBridge) != 0 || method.Name == "<clinit>" {
+			continue
+		}
+		writeMethod(b, class, flags, method, memberIndent)
+	}
+	// Nested classes
+	for _, inner := range nested[class.Name] {
+		innerFlags := inner.InnerAccessFlags
+		if innerFlags&(accPrivate|accSynthetic) != 0 {
+			continue
+		}
+		b.WriteString("\n")
+		writeStub(b, inner, nested, memberIndent)
+	}
+	b.WriteString(indent + "}\n")
+}
+
+// renderStub renders a top-level class as a Java source file.
+func renderStub(class *classFile, nested map[string][]*classFile, archive string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by rtfm from %s (no sources available)\n", filepath.Base(archive))
+	if i := strings.LastIndex(class.Name, "/"); i >= 0 {
+		fmt.Fprintf(&b, "package %s;\n\n", strings.ReplaceAll(class.Name[:i], "/", "."))
+	}
+	writeStub(&b, class, nested, "")
+	return b.String()
+}
+
+func readClassFiles(archive string) (map[string]*classFile, error) {
+	paths, err := common.ListArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	classes := make(map[string]*classFile)
+	for _, path := range paths {
+		_, entry, _ := common.SplitArchivePath(path)
+		name := filepath.Base(entry)
+		if !strings.HasSuffix(entry, ".class") || strings.HasPrefix(entry, "META-INF/") ||
+			name == "module-info.class" || name == "package-info.class" {
+			continue
+		}
+		info, err := common.StatFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > maxClassFileSize {
+			continue
+		}
+		data, err := common.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		class, err := parseClassFile(data)
+		if err != nil {
+			slog.Debug("Error parsing class file", "archive", archive, "entry", entry, "error", err)
+			continue
+		}
+		if isValidClassName(class.Name) && !isAnonymousClass(class.Name) {
+			classes[class.Name] = class
+		}
+	}
+	return classes, nil
+}
+
+// generateStubs writes a Java stub for each public class in a binary jar, for
+// artifacts that were published without sources.
+func generateStubs(coords *MavenCoordinates, outputDir string) (string, error) {
+	slog.Debug("Generating stubs", "artifact", coords.Path)
+	// Replace any previous stubs of the artifact
+	dest := filepath.Join(outputDir, coords.OutputDir())
+	err := os.RemoveAll(dest)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dest, 0o755)
+	if err != nil {
+		return "", err
+	}
+	classes, err := readClassFiles(coords.Path)
+	if err != nil {
+		return "", err
+	}
+	// Group nested classes by their enclosing class
+	nested := make(map[string][]*classFile)
+	topLevel := make([]*classFile, 0)
+	for name, class := range classes {
+		outer := name[:max(strings.LastIndex(name, "$"), 0)]
+		if _, ok := classes[outer]; ok && class.IsInner {
+			nested[outer] = append(nested[outer], class)
+		} else if class.AccessFlags&accPublic != 0 && class.AccessFlags&accSynthetic == 0 {
+			topLevel = append(topLevel, class)
+		}
+	}
+	for _, inner := range nested {
+		slices.SortFunc(inner, func(a, b *classFile) int { return strings.Compare(a.Name, b.Name) })
+	}
+	// Write the stubs
+	for _, class := range topLevel {
+		path := filepath.Join(dest, filepath.FromSlash(class.Name)+".java")
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return "", err
+		}
+		err = os.WriteFile(path, []byte(renderStub(class, nested, coords.Path)), 0o644)
+		if err != nil {
+			return "", err
+		}
+	}
+	return dest, nil
+}
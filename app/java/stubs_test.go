@@ -0,0 +1,132 @@
+package java
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// classBuilder assembles minimal class files for tests.
+type classBuilder struct {
+	pool  []byte
+	count uint16
+}
+
+func (b *classBuilder) add(entry []byte) uint16 {
+	b.count++
+	b.pool = append(b.pool, entry...)
+	return b.count
+}
+
+func (b *classBuilder) utf8(s string) uint16 {
+	entry := binary.BigEndian.AppendUint16([]byte{constantUtf8}, uint16(len(s)))
+	return b.add(append(entry, s...))
+}
+
+func (b *classBuilder) class(name string) uint16 {
+	return b.add(binary.BigEndian.AppendUint16([]byte{constantClass}, b.utf8(name)))
+}
+
+func (b *classBuilder) integer(v uint32) uint16 {
+	return b.add(binary.BigEndian.AppendUint32([]byte{constantInteger}, v))
+}
+
+func u2(values ...uint16) []byte {
+	acc := []byte{}
+	for _, v := range values {
+		acc = binary.BigEndian.AppendUint16(acc, v)
+	}
+	return acc
+}
+
+func attribute(name uint16, data []byte) []byte {
+	acc := u2(name)
+	acc = binary.BigEndian.AppendUint32(acc, uint32(len(data)))
+	return append(acc, data...)
+}
+
+func buildWidgetClass() []byte {
+	b := &classBuilder{}
+	this := b.class("com/example/Widget")
+	super := b.class("java/lang/Object")
+	runnable := b.class("java/lang/Runnable")
+	ioException := b.class("java/io/IOException")
+	deprecated := b.utf8("Ljava/lang/Deprecated;")
+	annotations := b.utf8("RuntimeVisibleAnnotations")
+	constantValue := b.utf8("ConstantValue")
+	exceptions := b.utf8("Exceptions")
+	maxName, intType, ten := b.utf8("MAX"), b.utf8("I"), b.integer(10)
+	secretName := b.utf8("secret")
+	runName, runType := b.utf8("run"), b.utf8("()V")
+	findName, findType := b.utf8("find"), b.utf8("(Ljava/lang/String;[I)Ljava/util/List;")
+	// Header
+	data := binary.BigEndian.AppendUint32(nil, 0xCAFEBABE)
+	data = append(data, u2(0, 61, b.count+1)...)
+	data = append(data, b.pool...)
+	data = append(data, u2(accPublic, this, super, 1, runnable)...)
+	// Fields
+	data = append(data, u2(2)...)
+	data = append(data, u2(accPublic|accStatic|accFinal, maxName, intType, 1)...)
+	data = append(data, attribute(constantValue, u2(ten))...)
+	data = append(data, u2(accPrivate, secretName, intType, 0)...)
+	// Methods
+	data = append(data, u2(2)...)
+	data = append(data, u2(accPublic, runName, runType, 0)...)
+	data = append(data, u2(accPublic|accVarargs, findName, findType, 1)...)
+	data = append(data, attribute(exceptions, u2(1, ioException))...)
+	// Class attributes
+	data = append(data, u2(1)...)
+	data = append(data, attribute(annotations, u2(1, deprecated, 0))...)
+	return data
+}
+
+func TestRenderStub(t *testing.T) {
+	class, err := parseClassFile(buildWidgetClass())
+	if err != nil {
+		t.Fatalf("parseClassFile failed: %v", err)
+	}
+	stub := renderStub(class, nil, "/repo/widget-1.0.jar")
+	for _, expected := range []string{
+		"package com.example;",
+		"@Deprecated\npublic class Widget implements Runnable {",
+		"    public static final int MAX = 10;",
+		"    public void run();",
+		"    public java.util.List find(String arg0, int... arg1) throws java.io.IOException;",
+	} {
+		if !strings.Contains(stub, expected) {
+			t.Errorf("stub is missing %q:\n%s", expected, stub)
+		}
+	}
+	if strings.Contains(stub, "secret") {
+		t.Errorf("stub contains a private field:\n%s", stub)
+	}
+	// The stub is indexed like any other Java source
+	symbols, err := parseJavaSymbols("/stubs/com/example/Widget.java", stub)
+	if err != nil {
+		t.Fatalf("parseJavaSymbols failed: %v", err)
+	}
+	expected := []common.Symbol{
+		{Name: "com.example.Widget", Kind: common.Class, Line: 5},
+		{Name: "com.example.Widget.MAX", Kind: common.Constant, Line: 6},
+		{Name: "com.example.Widget.run", Kind: common.Method, Line: 7},
+		{Name: "com.example.Widget.find", Kind: common.Method, Line: 8},
+	}
+	if len(symbols) != len(expected) {
+		t.Fatalf("parseJavaSymbols returned unexpected symbols: %v", symbols)
+	}
+	for i, symbol := range symbols {
+		if symbol != expected[i] {
+			t.Errorf("unexpected symbol: got %v, expected %v", symbol, expected[i])
+		}
+	}
+}
+
+func TestParseClassFileTruncated(t *testing.T) {
+	data := buildWidgetClass()
+	_, err := parseClassFile(data[:len(data)/2])
+	if err == nil {
+		t.Errorf("parseClassFile accepted a truncated class file")
+	}
+}
@@ -21,71 +21,118 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/brandtg/rtfm/app/common"
 )
 
-func Index() error {
-	slog.Info("Indexing Javascript modules...")
-	// Connect to the database
-	db, err := common.OpenDB()
-	if err != nil {
-		return fmt.Errorf("error opening database: %w", err)
+const Language common.Language = "javascript"
+
+type Indexer struct{}
+
+func init() {
+	common.Register(Indexer{})
+}
+
+func (Indexer) Language() common.Language {
+	return Language
+}
+
+func (Indexer) CommentPrefix() string {
+	return "//"
+}
+
+func (Indexer) Lexer() string {
+	return "javascript"
+}
+
+// javaScriptRoot maps the files of a node_modules directory to their packages.
+type javaScriptRoot struct {
+	packages map[string]*JavaScriptPackage
+}
+
+// Roots returns the node_modules directories (including those in the project,
+// if it isn't in the home directory).
+func (Indexer) Roots(opts common.IndexOptions) ([]*common.Root, error) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return nil, fmt.Errorf("home environment variable not set")
+	}
+	dirs := []string{home}
+	if opts.Project != nil && !strings.HasPrefix(opts.Project.Dir, home+string(os.PathSeparator)) {
+		dirs = append(dirs, opts.Project.Dir)
+	}
+	roots := make([]*common.Root, 0)
+	for _, dir := range dirs {
+		nodeModulesDirs, err := findNodeModulesDirs(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, nodeModuleDir := range nodeModulesDirs {
+			slog.Info("Found node_modules", "path", nodeModuleDir)
+			roots = append(roots, &common.Root{Path: nodeModuleDir, Source: &javaScriptRoot{}})
+		}
 	}
-	defer db.Close()
-	// Find node_modules directories
-	nodeModulesDirs, err := findNodeModulesDirs()
+	return roots, nil
+}
+
+// Files lists the modules of each package in a node_modules directory.
+func (Indexer) Files(root *common.Root) ([]string, error) {
+	packages, err := findJavaScriptPackages(root.Path)
 	if err != nil {
-		slog.Error("Error finding node modules", "error", err)
-		return err
-	}
-	// Find packages in each node_modules directory
-	for _, nodeModuleDir := range nodeModulesDirs {
-		slog.Info("Found node_modules", "path", nodeModuleDir)
-		// Find packages in node_modules
-		var packages []*JavaScriptPackage
-		packages, err = findJavaScriptPackages(nodeModuleDir)
+		return nil, fmt.Errorf("error finding JavaScript packages: %w", err)
+	}
+	source := root.Source.(*javaScriptRoot)
+	source.packages = make(map[string]*JavaScriptPackage)
+	paths := make([]string, 0)
+	for _, pkg := range packages {
+		files, err := findJavaScriptFiles(pkg)
 		if err != nil {
-			slog.Error("Error finding JavaScript packages", "error", err)
-			return err
-		}
-		// Find modules in each package
-		for _, pkg := range packages {
-			// Find files in package
-			var files []string
-			files, err = findJavaScriptFiles(pkg)
-			if err != nil {
-				slog.Error("Error finding JavaScript files", "error", err)
-				return err
-			}
-			// Map files to JavaScriptModule
-			javascriptModules := make([]JavaScriptModule, 0)
-			for _, file := range files {
-				javaScriptModule := JavaScriptModule{
-					Path:    strings.Replace(file, nodeModuleDir, "", 1)[1:],
-					Package: pkg,
-				}
-				javascriptModules = append(javascriptModules, javaScriptModule)
-			}
-			// Create a search document for each module
-			documents := make([]*common.SearchDocument, 0)
-			for _, module := range javascriptModules {
-				doc := &common.SearchDocument{
-					Language: common.Javascript,
-					Name:     module.Path,
-					Path:     filepath.Join(pkg.NodeModulesDir, module.Path),
-				}
-				documents = append(documents, doc)
-			}
-			// Write to database
-			err = common.IndexDocuments(db, documents)
-			if err != nil {
-				return fmt.Errorf("error indexing documents: %w", err)
-			}
+			return nil, fmt.Errorf("error finding JavaScript files: %w", err)
 		}
+		for _, file := range files {
+			source.packages[file] = pkg
+		}
+		paths = append(paths, files...)
+	}
+	return paths, nil
+}
+
+// Parse creates a search document for the module, named by its path in
+// node_modules (e.g. lodash/get.js), and its symbols.
+func (Indexer) Parse(root *common.Root, path string, code []byte) ([]*common.SearchDocument, error) {
+	relPath, err := filepath.Rel(root.Path, path)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.ToSlash(relPath)
+	var pkgName, pkgVersion string
+	if pkg, ok := root.Source.(*javaScriptRoot).packages[path]; ok {
+		pkgName, pkgVersion = pkg.Name, pkg.Version
 	}
-	return nil
+	documents := []*common.SearchDocument{{
+		Language: Language,
+		Kind:     common.Module,
+		Name:     name,
+		Path:     path,
+		Package:  pkgName,
+		Version:  pkgVersion,
+		Root:     root.Path,
+	}}
+	for _, symbol := range parseJavaScriptSymbols(name, string(code)) {
+		documents = append(documents, &common.SearchDocument{
+			Language: Language,
+			Kind:     symbol.Kind,
+			Name:     symbol.Name,
+			Path:     path,
+			Line:     symbol.Line,
+			Package:  pkgName,
+			Version:  pkgVersion,
+			Root:     root.Path,
+		})
+	}
+	return documents, nil
 }
 
 func isNestedNodeModules(path string) bool {
@@ -104,13 +151,9 @@ func shouldExclude(path string) bool {
 	return strings.Contains(path, "/anaconda3/")
 }
 
-func findNodeModulesDirs() ([]string, error) {
-	home := os.Getenv("HOME")
-	if home == "" {
-		return nil, fmt.Errorf("home environment variable not set")
-	}
+func findNodeModulesDirs(dir string) ([]string, error) {
 	nodeModules := make([]string, 0)
-	err := filepath.WalkDir(home, func(path string, d os.DirEntry, err error) error {
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 		// Skip hidden directories
 		if d.IsDir() && d.Name()[0] == '.' {
 			slog.Debug("Skipping hidden directory", "path", path)
@@ -148,11 +191,6 @@ func (p *JavaScriptPackage) fullPath() string {
 	return filepath.Join(p.NodeModulesDir, p.Path)
 }
 
-type JavaScriptModule struct {
-	Path    string
-	Package *JavaScriptPackage
-}
-
 func parsePackageJSON(nodeModulesDir string, path string) (*JavaScriptPackage, error) {
 	// Open file
 	file, err := os.Open(path)
@@ -233,3 +271,71 @@ func findJavaScriptFiles(pkg *JavaScriptPackage) ([]string, error) {
 	}
 	return files, nil
 }
+
+// Declarations are only recognized at the top level of a module (or directly
+// inside a top-level class), which is where a library's API is defined.
+var (
+	declarationPrefix = `^(?:export\s+)?(?:default\s+)?(?:declare\s+)?`
+	functionRegex     = regexp.MustCompile(declarationPrefix + `(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`)
+	classRegex        = regexp.MustCompile(declarationPrefix + `(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)
+	interfaceRegex    = regexp.MustCompile(declarationPrefix + `interface\s+([A-Za-z_$][\w$]*)`)
+	typeRegex         = regexp.MustCompile(declarationPrefix + `type\s+([A-Za-z_$][\w$]*)\s*(?:<[^=]*>)?\s*=`)
+	enumRegex         = regexp.MustCompile(declarationPrefix + `(?:const\s+)?enum\s+([A-Za-z_$][\w$]*)`)
+	exportedVarRegex  = regexp.MustCompile(`^export\s+(?:declare\s+)?(const|let|var)\s+([A-Za-z_$][\w$]*)`)
+	commonJSRegex     = regexp.MustCompile(`^(?:module\.)?exports\.([A-Za-z_$][\w$]*)\s*=`)
+	methodRegex       = regexp.MustCompile(
+		`^\s+(?:(?:public|private|protected|static|async|get|set|readonly|abstract)\s+)*\*?([A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\([^)]*\)?[^;]*\{\s*$`)
+	jsKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "catch": true, "function": true,
+		"return": true, "with": true,
+	}
+)
+
+func parseJavaScriptSymbols(moduleName string, code string) []common.Symbol {
+	symbols := make([]common.Symbol, 0)
+	className := ""
+	memberIndent := -1
+	for i, line := range strings.Split(code, "\n") {
+		symbol := common.Symbol{Line: i + 1}
+		// A top-level closing brace ends the current class
+		if strings.HasPrefix(line, "}") {
+			className = ""
+			continue
+		}
+		// Class members are indented like the first line of the class body
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if className != "" && memberIndent < 0 && strings.TrimSpace(line) != "" {
+			memberIndent = indent
+		}
+		if match := classRegex.FindStringSubmatch(line); match != nil {
+			symbol.Name, symbol.Kind = match[1], common.Class
+			if strings.HasSuffix(strings.TrimSpace(line), "{") {
+				className = match[1]
+				memberIndent = -1
+			}
+		} else if match := functionRegex.FindStringSubmatch(line); match != nil {
+			symbol.Name, symbol.Kind = match[1], common.Function
+		} else if match := interfaceRegex.FindStringSubmatch(line); match != nil {
+			symbol.Name, symbol.Kind = match[1], common.Interface
+		} else if match := typeRegex.FindStringSubmatch(line); match != nil {
+			symbol.Name, symbol.Kind = match[1], common.Type
+		} else if match := enumRegex.FindStringSubmatch(line); match != nil {
+			symbol.Name, symbol.Kind = match[1], common.Enum
+		} else if match := exportedVarRegex.FindStringSubmatch(line); match != nil {
+			symbol.Name, symbol.Kind = match[2], common.Variable
+			if match[1] == "const" {
+				symbol.Kind = common.Constant
+			}
+		} else if match := commonJSRegex.FindStringSubmatch(line); match != nil {
+			symbol.Name, symbol.Kind = match[1], common.Variable
+		} else if match := methodRegex.FindStringSubmatch(line); match != nil &&
+			className != "" && indent == memberIndent && !jsKeywords[match[1]] {
+			symbol.Name, symbol.Kind = className+"."+match[1], common.Method
+		}
+		if symbol.Name != "" {
+			symbol.Name = moduleName + ":" + symbol.Name
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
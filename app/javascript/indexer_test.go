@@ -0,0 +1,60 @@
+package javascript
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseJavaScriptSymbols(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected []string
+	}{
+		// Functions and classes, exported or not
+		{"function get(object, path) {", []string{"1 6 lodash/get.js:get"}},
+		{"export default async function* walk() {", []string{"1 6 lodash/get.js:walk"}},
+		{"export abstract class Base {}", []string{"1 1 lodash/get.js:Base"}},
+		// TypeScript declarations
+		{"export interface Options {", []string{"1 2 lodash/get.js:Options"}},
+		{"export type Path<T> = string[]", []string{"1 5 lodash/get.js:Path"}},
+		{"export declare const enum Mode {", []string{"1 3 lodash/get.js:Mode"}},
+		// Exported variables and CommonJS exports
+		{"export const VERSION = '4.17.21'", []string{"1 8 lodash/get.js:VERSION"}},
+		{"export let cache = new Map()", []string{"1 9 lodash/get.js:cache"}},
+		{"module.exports.get = get", []string{"1 9 lodash/get.js:get"}},
+		{"exports.set = set", []string{"1 9 lodash/get.js:set"}},
+		// Local variables and indented functions aren't part of the API
+		{"const local = 1\n  function nested() {", []string{}},
+		// Methods of top-level classes, at the indentation of the class body
+		{`export class Client {
+  constructor(options) {
+    if (options) {
+    }
+  }
+
+  static async request(url) {
+    for (const x of y) {
+    }
+  }
+  get timeout() {
+  }
+}
+function after() {}`, []string{
+			"1 1 lodash/get.js:Client",
+			"2 7 lodash/get.js:Client.constructor",
+			"7 7 lodash/get.js:Client.request",
+			"11 7 lodash/get.js:Client.timeout",
+			"14 6 lodash/get.js:after",
+		}},
+	}
+	for _, test := range tests {
+		actual := make([]string, 0)
+		for _, symbol := range parseJavaScriptSymbols("lodash/get.js", test.code) {
+			actual = append(actual, fmt.Sprintf("%d %d %s", symbol.Line, symbol.Kind, symbol.Name))
+		}
+		if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%q: expected %v, got %v", test.code, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package javascript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Dependencies reads the installed package versions from the project's
+// package-lock.json, yarn.lock or pnpm-lock.yaml.
+func (Indexer) Dependencies(projectDir string) ([]common.Dependency, error) {
+	parsers := []struct {
+		name  string
+		parse func(io.Reader) ([]common.Dependency, error)
+	}{
+		{"package-lock.json", parsePackageLock},
+		{"yarn.lock", parseYarnLock},
+		{"pnpm-lock.yaml", parsePnpmLock},
+	}
+	acc := make([]common.Dependency, 0)
+	for _, parser := range parsers {
+		file, err := os.Open(filepath.Join(projectDir, parser.name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dependencies, err := parser.parse(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", parser.name, err)
+		}
+		acc = append(acc, dependencies...)
+	}
+	return acc, nil
+}
+
+type packageLockEntry struct {
+	Name         string                      `json:"name"`
+	Version      string                      `json:"version"`
+	Dependencies map[string]packageLockEntry `json:"dependencies"`
+}
+
+// parsePackageLock reads the "packages" of lockfile v2 and v3 (keyed by their
+// path, e.g. node_modules/a/node_modules/b), or the nested "dependencies" of v1.
+func parsePackageLock(r io.Reader) ([]common.Dependency, error) {
+	var lock struct {
+		Packages     map[string]packageLockEntry `json:"packages"`
+		Dependencies map[string]packageLockEntry `json:"dependencies"`
+	}
+	err := json.NewDecoder(r).Decode(&lock)
+	if err != nil {
+		return nil, err
+	}
+	acc := make([]common.Dependency, 0)
+	if lock.Packages != nil {
+		for path, entry := range lock.Packages {
+			i := strings.LastIndex(path, "node_modules/")
+			if i < 0 {
+				// The project itself
+				continue
+			}
+			name := entry.Name
+			if name == "" {
+				name = path[i+len("node_modules/"):]
+			}
+			acc = append(acc, common.Dependency{Language: Language, Package: name, Version: entry.Version})
+		}
+		return acc, nil
+	}
+	var walk func(dependencies map[string]packageLockEntry)
+	walk = func(dependencies map[string]packageLockEntry) {
+		for name, entry := range dependencies {
+			acc = append(acc, common.Dependency{Language: Language, Package: name, Version: entry.Version})
+			walk(entry.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+	return acc, nil
+}
+
+// yarnPackageName returns the name of a yarn.lock entry's first specifier
+// (e.g. "@babel/core@^7.0.0, @babel/core@^7.1.0:" -> @babel/core).
+func yarnPackageName(line string) string {
+	spec, _, _ := strings.Cut(strings.TrimSuffix(line, ":"), ",")
+	spec = strings.Trim(strings.TrimSpace(spec), `"`)
+	if i := strings.Index(spec[min(1, len(spec)):], "@"); i >= 0 {
+		return spec[:i+1]
+	}
+	return spec
+}
+
+// parseYarnLock reads the entries of yarn.lock, in the format of yarn v1
+// (version "1.2.3") and yarn berry (version: 1.2.3).
+func parseYarnLock(r io.Reader) ([]common.Dependency, error) {
+	acc := make([]common.Dependency, 0)
+	name := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Entries start at the first column, and their fields are indented
+		if line[0] != ' ' {
+			name = yarnPackageName(line)
+			if name == "__metadata" {
+				name = ""
+			}
+			continue
+		}
+		field := strings.TrimSpace(line)
+		if name != "" && strings.HasPrefix(field, "version") && !strings.HasPrefix(field, "versions") {
+			version := strings.TrimPrefix(strings.TrimPrefix(field, "version"), ":")
+			version = strings.Trim(strings.TrimSpace(version), `"`)
+			acc = append(acc, common.Dependency{Language: Language, Package: name, Version: version})
+			name = ""
+		}
+	}
+	return acc, scanner.Err()
+}
+
+// parsePnpmPackageKey parses the key of a package in pnpm-lock.yaml, which is
+// /name/version in v5, /name@version in v6, and name@version in v9, followed
+// by any peer dependencies in parentheses.
+func parsePnpmPackageKey(key string) (string, string) {
+	key = strings.TrimSuffix(strings.TrimSpace(key), ":")
+	key = strings.Trim(key, `'"`)
+	key, _, _ = strings.Cut(key, "(")
+	v5 := strings.HasPrefix(key, "/")
+	key = strings.TrimPrefix(key, "/")
+	if i := strings.LastIndex(key, "@"); i > 0 {
+		return key[:i], key[i+1:]
+	}
+	if i := strings.LastIndex(key, "/"); v5 && i > 0 {
+		name, version := key[:i], key[i+1:]
+		version, _, _ = strings.Cut(version, "_")
+		return name, version
+	}
+	return "", ""
+}
+
+// parsePnpmLock reads the keys of the top-level packages section of
+// pnpm-lock.yaml.
+func parsePnpmLock(r io.Reader) ([]common.Dependency, error) {
+	acc := make([]common.Dependency, 0)
+	inPackages := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if line[0] != ' ' {
+			inPackages = line == "packages:"
+			continue
+		}
+		// Package keys are indented by two spaces, their fields by more
+		if !inPackages || !strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "   ") {
+			continue
+		}
+		name, version := parsePnpmPackageKey(line)
+		if name != "" {
+			acc = append(acc, common.Dependency{Language: Language, Package: name, Version: version})
+		}
+	}
+	return acc, scanner.Err()
+}
@@ -0,0 +1,117 @@
+package javascript
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func dependencyStrings(dependencies []common.Dependency) []string {
+	acc := make([]string, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		acc = append(acc, dependency.Package+"@"+dependency.Version)
+	}
+	slices.Sort(acc)
+	return acc
+}
+
+func TestParseLockfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		parse    func(io.Reader) ([]common.Dependency, error)
+		lockfile string
+		expected []string
+	}{
+		{
+			name:  "package-lock.json v3",
+			parse: parsePackageLock,
+			lockfile: `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": {"name": "app", "version": "1.0.0"},
+					"node_modules/@babel/core": {"version": "7.24.0"},
+					"node_modules/a/node_modules/lodash": {"version": "3.10.1"}
+				}
+			}`,
+			expected: []string{"@babel/core@7.24.0", "lodash@3.10.1"},
+		},
+		{
+			name:  "package-lock.json v1",
+			parse: parsePackageLock,
+			lockfile: `{
+				"lockfileVersion": 1,
+				"dependencies": {
+					"a": {"version": "1.0.0", "dependencies": {"lodash": {"version": "3.10.1"}}}
+				}
+			}`,
+			expected: []string{"a@1.0.0", "lodash@3.10.1"},
+		},
+		{
+			name:  "yarn.lock v1",
+			parse: parseYarnLock,
+			lockfile: `# yarn lockfile v1
+
+"@babel/core@^7.0.0", "@babel/core@^7.1.0":
+  version "7.24.0"
+  dependencies:
+    debug "^4.1.0"
+
+lodash@^4.17.21:
+  version "4.17.21"
+`,
+			expected: []string{"@babel/core@7.24.0", "lodash@4.17.21"},
+		},
+		{
+			name:  "yarn.lock berry",
+			parse: parseYarnLock,
+			lockfile: `__metadata:
+  version: 8
+
+"lodash@npm:^4.17.21":
+  version: 4.17.21
+  resolution: "lodash@npm:4.17.21"
+`,
+			expected: []string{"lodash@4.17.21"},
+		},
+		{
+			name:  "pnpm-lock.yaml",
+			parse: parsePnpmLock,
+			lockfile: `lockfileVersion: '9.0'
+
+importers:
+  .:
+    dependencies:
+      lodash:
+        specifier: ^4.17.21
+        version: 4.17.21
+
+packages:
+  '@babel/core@7.24.0':
+    resolution: {integrity: sha512-x}
+  /debug/4.3.4:
+    resolution: {integrity: sha512-y}
+  /react-dom@18.2.0(react@18.2.0):
+    resolution: {integrity: sha512-z}
+
+snapshots:
+  lodash@4.17.21: {}
+`,
+			expected: []string{"@babel/core@7.24.0", "debug@4.3.4", "react-dom@18.2.0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dependencies, err := test.parse(strings.NewReader(test.lockfile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := dependencyStrings(dependencies)
+			if !slices.Equal(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const Version = "2.0"
+
+// Error codes defined by JSON-RPC 2.0
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is the error of a response. Handlers return it to choose the code.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+func MethodNotFound(method string) *Error {
+	return &Error{Code: CodeMethodNotFound, Message: "method not found: " + method}
+}
+
+func InvalidParams(err error) *Error {
+	return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+}
+
+// ErrClosed is returned by a handler to stop serving (e.g. on an exit
+// notification).
+var ErrClosed = errors.New("connection closed")
+
+// Message is a request, a notification (without an ID) or a response.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsNotification reports whether a message doesn't expect a response.
+func (m *Message) IsNotification() bool {
+	return len(m.ID) == 0
+}
+
+type resultResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *Error          `json:"error"`
+}
+
+// Stream reads and writes messages framed with Content-Length headers, as in
+// the Language Server Protocol, or one per line.
+type Stream struct {
+	reader *bufio.Reader
+	writer io.Writer
+	mutex  sync.Mutex
+	// lines frames messages with newlines instead of headers
+	lines bool
+}
+
+func NewStream(r io.Reader, w io.Writer) *Stream {
+	return &Stream{reader: bufio.NewReader(r), writer: w}
+}
+
+// NewLineStream returns a stream of newline delimited messages, as in the
+// stdio transport of the Model Context Protocol.
+func NewLineStream(r io.Reader, w io.Writer) *Stream {
+	return &Stream{reader: bufio.NewReader(r), writer: w, lines: true}
+}
+
+// Read reads the next message, or returns io.EOF at the end of the stream.
+func (s *Stream) Read() (*Message, error) {
+	var body []byte
+	var err error
+	if s.lines {
+		body, err = s.readLine()
+	} else {
+		body, err = s.readFrame()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var message Message
+	err = json.Unmarshal(body, &message)
+	if err != nil {
+		return nil, &Error{Code: CodeParseError, Message: err.Error()}
+	}
+	return &message, nil
+}
+
+// readLine reads the next non-empty line.
+func (s *Stream) readLine() ([]byte, error) {
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			// The last message may not end with a newline
+			return line, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading message: %w", err)
+		}
+	}
+}
+
+// readFrame reads the body of the next message with headers.
+func (s *Stream) readFrame() ([]byte, error) {
+	headers, err := textproto.NewReader(s.reader).ReadMIMEHeader()
+	if errors.Is(err, io.EOF) && len(headers) == 0 {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading headers: %w", err)
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length: %q", headers.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(s.reader, body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading message: %w", err)
+	}
+	return body, nil
+}
+
+// Write writes a message.
+func (s *Stream) Write(message any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.lines {
+		// Marshaled JSON never contains a raw newline
+		_, err = s.writer.Write(append(body, '\n'))
+		return err
+	}
+	_, err = fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// Handler returns the result of a request or notification.
+type Handler func(method string, params json.RawMessage) (any, error)
+
+// Serve handles messages one at a time until the stream ends, or the handler
+// returns ErrClosed. Errors of notifications are only logged.
+func Serve(stream *Stream, handler Handler) error {
+	for {
+		message, err := stream.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			// The message can't be answered without its ID
+			err = stream.Write(errorResponse{JSONRPC: Version, ID: json.RawMessage("null"), Error: rpcErr})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Ignore responses, since requests are never sent to the client
+		if message.Method == "" {
+			continue
+		}
+		result, err := handler(message.Method, message.Params)
+		if errors.Is(err, ErrClosed) {
+			return nil
+		}
+		if message.IsNotification() {
+			if err != nil && !strings.HasPrefix(message.Method, "$/") {
+				slog.Warn("Error handling notification", "method", message.Method, "error", err)
+			}
+			continue
+		}
+		if err != nil {
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+			}
+			err = stream.Write(errorResponse{JSONRPC: Version, ID: message.ID, Error: rpcErr})
+		} else {
+			err = stream.Write(resultResponse{JSONRPC: Version, ID: message.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
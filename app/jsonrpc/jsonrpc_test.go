@@ -0,0 +1,78 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func frame(messages ...string) string {
+	var b strings.Builder
+	for _, message := range messages {
+		b.WriteString("Content-Length: " + strconv.Itoa(len(message)) + "\r\n\r\n" + message)
+	}
+	return b.String()
+}
+
+func TestServe(t *testing.T) {
+	input := frame(
+		`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"héllo"}}`,
+		`{"jsonrpc":"2.0","method":"notify"}`,
+		`{"jsonrpc":"2.0","id":"a","method":"missing"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"fail"}`,
+		`{not json}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"echo"}`,
+	)
+	var output bytes.Buffer
+	notified := false
+	err := Serve(NewStream(strings.NewReader(input), &output), func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "echo":
+			return params, nil
+		case "notify":
+			notified = true
+			return nil, nil
+		case "fail":
+			return nil, errors.New("failed")
+		case "exit":
+			return nil, ErrClosed
+		}
+		return nil, MethodNotFound(method)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !notified {
+		t.Error("notification was not handled")
+	}
+	// Every request before exit gets a response, in order
+	expected := frame(
+		`{"jsonrpc":"2.0","id":1,"result":{"text":"héllo"}}`,
+		`{"jsonrpc":"2.0","id":"a","error":{"code":-32601,"message":"method not found: missing"}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"failed"}}`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"invalid character 'n' looking for beginning of object key string"}}`,
+	)
+	if output.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output.String())
+	}
+}
+
+func TestLineStream(t *testing.T) {
+	input := "{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"echo\",\"params\":\"a\\nb\"}\n\r\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"echo","params":[1]}`
+	var output bytes.Buffer
+	err := Serve(NewLineStream(strings.NewReader(input), &output), func(method string, params json.RawMessage) (any, error) {
+		return params, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"jsonrpc":"2.0","id":1,"result":"a\nb"}` + "\n" + `{"jsonrpc":"2.0","id":2,"result":[1]}` + "\n"
+	if output.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output.String())
+	}
+}
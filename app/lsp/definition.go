@@ -0,0 +1,150 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Maximum number of locations of textDocument/definition
+const maxDefinitions = 20
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// symbolAt returns the identifier at a position in a text, and the dotted
+// expression that ends with it (e.g. Client and http.Client). Characters are
+// counted in runes, which matches UTF-16 offsets for most code.
+func symbolAt(text string, position Position) (string, string) {
+	lines := strings.Split(text, "\n")
+	if position.Line < 0 || position.Line >= len(lines) {
+		return "", ""
+	}
+	line := []rune(strings.TrimSuffix(lines[position.Line], "\r"))
+	column := min(max(position.Character, 0), len(line))
+	start, end := column, column
+	for start > 0 && isIdentifierRune(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isIdentifierRune(line[end]) {
+		end++
+	}
+	if start == end {
+		return "", ""
+	}
+	// Include the qualifiers before the identifier
+	qualifiedStart := start
+	for qualifiedStart > 1 && line[qualifiedStart-1] == '.' && isIdentifierRune(line[qualifiedStart-2]) {
+		qualifiedStart--
+		for qualifiedStart > 0 && isIdentifierRune(line[qualifiedStart-1]) {
+			qualifiedStart--
+		}
+	}
+	return string(line[start:end]), string(line[qualifiedStart:end])
+}
+
+// matchesSymbol reports whether a document name ends with a symbol, after a
+// separator of the naming scheme of its language (e.g. java.util.Map.Entry,
+// net/http.Client or lodash/get.js:get).
+func matchesSymbol(name string, symbol string) bool {
+	if !strings.HasSuffix(name, symbol) {
+		return false
+	}
+	if len(name) == len(symbol) {
+		return true
+	}
+	return strings.ContainsRune(".:/$", rune(name[len(name)-len(symbol)-1]))
+}
+
+// parentName returns the name of the module or class a document is in (e.g.
+// java.util.Map for java.util.Map.Entry, lodash/get for lodash/get.js:get).
+func parentName(name string) string {
+	i := strings.LastIndexAny(name, ".:")
+	if i <= 0 {
+		return ""
+	}
+	parent := name[:i]
+	if name[i] == ':' {
+		parent = strings.TrimSuffix(parent, path.Ext(parent))
+	}
+	return parent
+}
+
+// definitionScore ranks a candidate definition of the symbol at a position:
+// documents that match the whole expression (http.Client), and documents
+// whose module is imported by the file.
+func definitionScore(doc *common.SearchDocument, symbol string, qualified string, text string) int {
+	score := 0
+	if qualified != symbol && matchesSymbol(doc.Name, qualified) {
+		score += 4
+	}
+	if strings.Contains(text, doc.Name) {
+		score += 2
+	} else if parent := parentName(doc.Name); parent != "" && strings.Contains(text, parent) {
+		score += 2
+	}
+	return score
+}
+
+// definition finds the documents that define the symbol at a position, in the
+// language of the file.
+func (s *Server) definition(params definitionParams) ([]Location, error) {
+	text, language, err := s.documentText(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	symbol, qualified := symbolAt(text, params.Position)
+	if symbol == "" {
+		return nil, nil
+	}
+	docs, err := s.findDocuments(common.DocumentFilter{Language: language}, "%"+symbol, common.MatchLike)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the best matches
+	type candidate struct {
+		doc   *common.SearchDocument
+		score int
+	}
+	candidates := make([]candidate, 0)
+	for _, doc := range docs {
+		if matchesSymbol(doc.Name, symbol) {
+			candidates = append(candidates, candidate{doc, definitionScore(doc, symbol, qualified, text)})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return cmp.Compare(b.score, a.score) })
+	acc := make([]Location, 0)
+	for _, c := range candidates {
+		if c.score < candidates[0].score || len(acc) == maxDefinitions {
+			break
+		}
+		loc, err := location(c.doc)
+		if err != nil {
+			continue
+		}
+		acc = append(acc, loc)
+	}
+	return acc, nil
+}
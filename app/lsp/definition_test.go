@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func TestSymbolAt(t *testing.T) {
+	text := "package main\n\nfunc main() {\n\tc := http.Client{}\n\tm := Map.Entry\n}\n"
+	tests := []struct {
+		position  Position
+		symbol    string
+		qualified string
+	}{
+		{Position{Line: 3, Character: 12}, "Client", "http.Client"},
+		{Position{Line: 3, Character: 7}, "http", "http"},
+		{Position{Line: 4, Character: 15}, "Entry", "Map.Entry"},
+		{Position{Line: 3, Character: 3}, "", ""},
+		{Position{Line: 10, Character: 0}, "", ""},
+	}
+	for _, test := range tests {
+		symbol, qualified := symbolAt(text, test.position)
+		if symbol != test.symbol || qualified != test.qualified {
+			t.Errorf("%+v: expected %q %q, got %q %q", test.position, test.symbol, test.qualified, symbol, qualified)
+		}
+	}
+}
+
+func TestMatchesSymbol(t *testing.T) {
+	tests := []struct {
+		name     string
+		symbol   string
+		expected bool
+	}{
+		{"java.util.Map.Entry", "Entry", true},
+		{"java.util.Map.Entry", "Map.Entry", true},
+		{"net/http.Client", "http.Client", true},
+		{"lodash/get.js:get", "get", true},
+		{"requests.api.get", "get", true},
+		{"requests.api.target", "get", false},
+	}
+	for _, test := range tests {
+		if actual := matchesSymbol(test.name, test.symbol); actual != test.expected {
+			t.Errorf("%s %s: expected %v", test.name, test.symbol, test.expected)
+		}
+	}
+}
+
+func TestDefinitionScore(t *testing.T) {
+	text := "import java.util.Map;\n\nMap.Entry e;\n"
+	imported := &common.SearchDocument{Name: "java.util.Map.Entry"}
+	other := &common.SearchDocument{Name: "com.google.common.collect.Table.Entry"}
+	if definitionScore(imported, "Entry", "Map.Entry", text) <= definitionScore(other, "Entry", "Map.Entry", text) {
+		t.Error("expected the imported class to rank first")
+	}
+	text = "import get from 'lodash/get';\n"
+	if definitionScore(&common.SearchDocument{Name: "lodash/get.js:get"}, "get", "get", text) != 2 {
+		t.Error("expected the imported module to rank first")
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Types of the Language Server Protocol that rtfm uses
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type SymbolKind int
+
+// Symbol kinds of the protocol that rtfm documents map to
+const (
+	SymbolFile          SymbolKind = 1
+	SymbolModule        SymbolKind = 2
+	SymbolClass         SymbolKind = 5
+	SymbolMethod        SymbolKind = 6
+	SymbolEnum          SymbolKind = 10
+	SymbolInterface     SymbolKind = 11
+	SymbolFunction      SymbolKind = 12
+	SymbolVariable      SymbolKind = 13
+	SymbolConstant      SymbolKind = 14
+	SymbolStruct        SymbolKind = 23
+	SymbolTypeParameter SymbolKind = 26
+)
+
+func symbolKind(kind common.Kind) SymbolKind {
+	switch kind {
+	case common.Module:
+		return SymbolModule
+	case common.Class:
+		return SymbolClass
+	case common.Interface:
+		return SymbolInterface
+	case common.Enum:
+		return SymbolEnum
+	case common.Record:
+		return SymbolStruct
+	case common.Type:
+		return SymbolTypeParameter
+	case common.Function:
+		return SymbolFunction
+	case common.Method:
+		return SymbolMethod
+	case common.Constant:
+		return SymbolConstant
+	case common.Variable:
+		return SymbolVariable
+	}
+	return SymbolFile
+}
+
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+	// Data is kept by the client for workspaceSymbol/resolve
+	Data *symbolData `json:"data,omitempty"`
+}
+
+type symbolData struct {
+	ID int64 `json:"id"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type initializeParams struct {
+	RootURI          string `json:"rootUri"`
+	RootPath         string `json:"rootPath"`
+	WorkspaceFolders []struct {
+		URI string `json:"uri"`
+	} `json:"workspaceFolders"`
+}
+
+type serverInfo struct {
+	Name string `json:"name"`
+}
+
+type serverCapabilities struct {
+	// TextDocumentSync is 1 to receive the full text of open documents
+	TextDocumentSync        int                    `json:"textDocumentSync"`
+	DefinitionProvider      bool                   `json:"definitionProvider"`
+	WorkspaceSymbolProvider workspaceSymbolOptions `json:"workspaceSymbolProvider"`
+}
+
+type workspaceSymbolOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+type didOpenParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   TextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type workspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type definitionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// fileURI returns the file URI of a path.
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// archiveEntryURI returns the jar URI of an entry in an archive (e.g.
+// jar:file:///m2/guava-sources.jar!/com/google/common/base/Strings.java).
+func archiveEntryURI(archive string, entry string) string {
+	return "jar:" + fileURI(archive) + common.ArchiveSeparator + entry
+}
+
+// pathFromURI returns the path of a file URI.
+func pathFromURI(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("not a file URI: %s", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
@@ -0,0 +1,286 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+	"github.com/brandtg/rtfm/app/jsonrpc"
+)
+
+// Maximum number of results of workspace/symbol
+const maxSymbols = 100
+
+// Server answers symbol and definition requests from the rtfm index, as a
+// secondary language server for library code.
+type Server struct {
+	db *sql.DB
+	// project limits results to the library versions the workspace uses, if
+	// it has lockfiles
+	project *common.Project
+	// documents are the open files, by URI
+	documents map[string]TextDocumentItem
+	shutdown  bool
+}
+
+func NewServer(db *sql.DB) *Server {
+	return &Server{db: db, documents: make(map[string]TextDocumentItem)}
+}
+
+// Serve runs a language server over a stream (e.g. stdin and stdout) until
+// the client exits.
+func Serve(r io.Reader, w io.Writer) error {
+	db, err := common.OpenDB()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+	server := NewServer(db)
+	err = jsonrpc.Serve(jsonrpc.NewStream(r, w), server.Handle)
+	if err != nil {
+		return err
+	}
+	if !server.shutdown {
+		return fmt.Errorf("client exited without a shutdown request")
+	}
+	return nil
+}
+
+// decode unmarshals the params of a request.
+func decode(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(params, v)
+	if err != nil {
+		return jsonrpc.InvalidParams(err)
+	}
+	return nil
+}
+
+// Handle handles a request or notification from the client.
+func (s *Server) Handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p initializeParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		s.initialize(p)
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:        1,
+				DefinitionProvider:      true,
+				WorkspaceSymbolProvider: workspaceSymbolOptions{ResolveProvider: true},
+			},
+			ServerInfo: serverInfo{Name: "rtfm"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, jsonrpc.ErrClosed
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		s.documents[p.TextDocument.URI] = p.TextDocument
+		return nil, nil
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		// Changes contain the full text, as requested in the capabilities
+		if doc, ok := s.documents[p.TextDocument.URI]; ok && len(p.ContentChanges) > 0 {
+			doc.Text = p.ContentChanges[len(p.ContentChanges)-1].Text
+			s.documents[p.TextDocument.URI] = doc
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.documents, p.TextDocument.URI)
+		return nil, nil
+	case "workspace/symbol":
+		var p workspaceSymbolParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		return s.symbols(p.Query)
+	case "workspaceSymbol/resolve":
+		var p SymbolInformation
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		return s.resolveSymbol(p)
+	case "textDocument/definition":
+		var p definitionParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		return s.definition(p)
+	case "initialized":
+		return nil, nil
+	}
+	return nil, jsonrpc.MethodNotFound(method)
+}
+
+// initialize scopes results to the workspace's dependencies, if it has
+// lockfiles.
+func (s *Server) initialize(params initializeParams) {
+	root := params.RootPath
+	if params.RootURI != "" {
+		root, _ = pathFromURI(params.RootURI)
+	} else if len(params.WorkspaceFolders) > 0 {
+		root, _ = pathFromURI(params.WorkspaceFolders[0].URI)
+	}
+	if root == "" {
+		return
+	}
+	project, err := common.LoadProject(root)
+	if err != nil {
+		slog.Info("Not scoping results to a project", "root", root, "error", err)
+		return
+	}
+	s.project = project
+}
+
+// findDocuments finds documents in the index, from the libraries the project
+// uses.
+func (s *Server) findDocuments(filter common.DocumentFilter, query string, match common.Match) ([]*common.SearchDocument, error) {
+	docs, err := common.FindDocuments(s.db, filter, query, match)
+	if err != nil {
+		return nil, err
+	}
+	if s.project != nil {
+		docs = slices.DeleteFunc(docs, func(doc *common.SearchDocument) bool { return !s.project.Includes(doc) })
+	}
+	return docs, nil
+}
+
+// location returns the location of a document, extracting it first if it is
+// in an archive (for definitions and resolved symbols).
+func location(doc *common.SearchDocument) (Location, error) {
+	path, err := common.MaterializeFile(doc.Path)
+	if err != nil {
+		return Location{}, err
+	}
+	position := Position{Line: max(doc.Line-1, 0)}
+	return Location{URI: fileURI(path), Range: Range{Start: position, End: position}}, nil
+}
+
+// containerName describes the library of a document (e.g. lodash@4.17.21).
+func containerName(doc *common.SearchDocument) string {
+	if doc.Version == "" {
+		return doc.Package
+	}
+	return doc.Package + "@" + doc.Version
+}
+
+// symbols finds documents whose names match the query, best matches first.
+func (s *Server) symbols(query string) ([]SymbolInformation, error) {
+	acc := make([]SymbolInformation, 0)
+	if strings.TrimSpace(query) == "" {
+		return acc, nil
+	}
+	docs, err := s.findDocuments(common.DocumentFilter{}, query, common.MatchQuery)
+	if err != nil {
+		return nil, err
+	}
+	// Clients query symbols as the user types, so entries in archives are
+	// only extracted once a symbol is resolved
+	for _, doc := range docs[:min(len(docs), maxSymbols)] {
+		uri := fileURI(doc.Path)
+		if archive, entry, ok := common.SplitArchivePath(doc.Path); ok {
+			uri = archiveEntryURI(archive, entry)
+		}
+		position := Position{Line: max(doc.Line-1, 0)}
+		acc = append(acc, SymbolInformation{
+			Name:          doc.Name,
+			Kind:          symbolKind(doc.Kind),
+			Location:      Location{URI: uri, Range: Range{Start: position, End: position}},
+			ContainerName: containerName(doc),
+			Data:          &symbolData{ID: doc.ID},
+		})
+	}
+	return acc, nil
+}
+
+// resolveSymbol returns the location of a symbol the user picked as a file,
+// extracting it first if it is in an archive.
+func (s *Server) resolveSymbol(symbol SymbolInformation) (SymbolInformation, error) {
+	if symbol.Data == nil {
+		return symbol, nil
+	}
+	doc, err := common.FindDocument(s.db, symbol.Data.ID)
+	if err != nil {
+		return symbol, err
+	}
+	symbol.Location, err = location(doc)
+	return symbol, err
+}
+
+// documentText returns the text and language of a file, from the client if
+// it is open.
+func (s *Server) documentText(uri string) (string, common.Language, error) {
+	if doc, ok := s.documents[uri]; ok {
+		return doc.Text, languageFromID(doc.LanguageID, uri), nil
+	}
+	path, err := pathFromURI(uri)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return string(data), languageFromID("", uri), nil
+}
+
+// languageFromID returns the indexed language of a document, from its LSP
+// language ID or file extension ("" if unknown).
+func languageFromID(languageID string, uri string) common.Language {
+	switch languageID {
+	case "javascriptreact", "typescript", "typescriptreact":
+		return common.LanguageFromName("javascript")
+	case "":
+	default:
+		return common.LanguageFromName(languageID)
+	}
+	switch filepath.Ext(uri) {
+	case ".java":
+		return common.LanguageFromName("java")
+	case ".py", ".pyi":
+		return common.LanguageFromName("python")
+	case ".go":
+		return common.LanguageFromName("go")
+	case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs":
+		return common.LanguageFromName("javascript")
+	}
+	return ""
+}
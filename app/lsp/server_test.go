@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func TestSymbolsExtractOnResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	db, err := common.OpenDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer common.CloseArchives()
+	// A class in a sources jar
+	archive := filepath.Join(t.TempDir(), "gadget-sources.jar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	entry, _ := w.Create("com/example/Gadget.java")
+	entry.Write([]byte("package com.example;\n\npublic class Gadget {}\n"))
+	w.Close()
+	f.Close()
+	path := common.ArchivePath(archive, "com/example/Gadget.java")
+	err = common.IndexDocuments(db, []*common.SearchDocument{
+		{Language: "java", Kind: common.Class, Name: "com.example.Gadget", Path: path, Line: 3, Root: archive},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(db)
+	symbols, err := server.symbols("Gadget")
+	if err != nil || len(symbols) != 1 {
+		t.Fatalf("unexpected symbols: %+v (%v)", symbols, err)
+	}
+	// Symbols refer to the entry in the archive, without extracting it
+	expected := "jar:" + fileURI(archive) + "!/com/example/Gadget.java"
+	if symbols[0].Location.URI != expected || symbols[0].Location.Range.Start.Line != 2 {
+		t.Errorf("unexpected location: %+v", symbols[0].Location)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".local", "share", "rtfm", "archives")); !os.IsNotExist(err) {
+		t.Errorf("archive entries were extracted for workspace/symbol: %v", err)
+	}
+	// Resolving the symbol extracts it
+	resolved, err := server.resolveSymbol(symbols[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted, err := pathFromURI(resolved.Location.URI)
+	if err != nil || !strings.HasSuffix(extracted, filepath.FromSlash("com/example/Gadget.java")) {
+		t.Fatalf("unexpected resolved location: %+v (%v)", resolved.Location, err)
+	}
+	if data, err := os.ReadFile(extracted); err != nil || !strings.Contains(string(data), "class Gadget") {
+		t.Errorf("unexpected extracted file: %q (%v)", data, err)
+	}
+}
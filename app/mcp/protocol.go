@@ -0,0 +1,92 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import "encoding/json"
+
+// Types of the Model Context Protocol that rtfm uses
+// (https://modelcontextprotocol.io/specification).
+
+// protocolVersions are the supported protocol versions, latest first.
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+type implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type initializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	ClientInfo      implementation `json:"clientInfo"`
+}
+
+type toolsCapability struct {
+	ListChanged bool `json:"listChanged"`
+}
+
+type serverCapabilities struct {
+	Tools toolsCapability `json:"tools"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    serverCapabilities `json:"capabilities"`
+	ServerInfo      implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// schema is a JSON schema of tool arguments.
+type schema struct {
+	Type        string            `json:"type"`
+	Description string            `json:"description,omitempty"`
+	Properties  map[string]schema `json:"properties,omitempty"`
+	Required    []string          `json:"required,omitempty"`
+	Enum        []string          `json:"enum,omitempty"`
+}
+
+type tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema schema `json:"inputSchema"`
+}
+
+type listToolsResult struct {
+	Tools []tool `json:"tools"`
+}
+
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// callToolResult is the result of a tool. Errors of tools (e.g. a document that
+// doesn't exist) are results with IsError, so the model can see them.
+type callToolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError"`
+}
+
+func textResult(text string) callToolResult {
+	return callToolResult{Content: []content{{Type: "text", Text: text}}}
+}
+
+func errorResult(err error) callToolResult {
+	return callToolResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}
+}
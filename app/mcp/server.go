@@ -0,0 +1,100 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/brandtg/rtfm/app/common"
+	"github.com/brandtg/rtfm/app/jsonrpc"
+)
+
+const instructions = "rtfm indexes the source code of the libraries installed on this machine " +
+	"(node_modules, virtual environments, Maven and Gradle caches, Go modules and standard libraries). " +
+	"Use search_symbols to find where a class, function or module is defined, read_source to read it " +
+	"at the exact version that is installed, and list_packages to see which versions are available."
+
+// Server answers tool calls of coding assistants from the rtfm index.
+type Server struct {
+	db *sql.DB
+	// project limits results to the library versions a project uses
+	project *common.Project
+}
+
+func NewServer(db *sql.DB, project *common.Project) *Server {
+	return &Server{db: db, project: project}
+}
+
+// Serve runs an MCP server over stdio (newline delimited JSON-RPC) until the
+// client closes the stream.
+func Serve(r io.Reader, w io.Writer, project *common.Project) error {
+	db, err := common.OpenDB()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+	return jsonrpc.Serve(jsonrpc.NewLineStream(r, w), NewServer(db, project).Handle)
+}
+
+// decode unmarshals the params of a request.
+func decode(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	err := json.Unmarshal(params, v)
+	if err != nil {
+		return jsonrpc.InvalidParams(err)
+	}
+	return nil
+}
+
+// Handle handles a request or notification from the client.
+func (s *Server) Handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p initializeParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		// Use the client's version if it is supported, or else the latest
+		version := protocolVersions[0]
+		if slices.Contains(protocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		return initializeResult{
+			ProtocolVersion: version,
+			Capabilities:    serverCapabilities{Tools: toolsCapability{}},
+			ServerInfo:      implementation{Name: "rtfm"},
+			Instructions:    instructions,
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return listToolsResult{Tools: tools()}, nil
+	case "tools/call":
+		var p callToolParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		return s.callTool(p)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	}
+	return nil, jsonrpc.MethodNotFound(method)
+}
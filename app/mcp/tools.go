@@ -0,0 +1,312 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+	"github.com/brandtg/rtfm/app/jsonrpc"
+)
+
+const (
+	// Default and maximum number of results of search_symbols
+	defaultSymbols = 20
+	maxSymbols     = 100
+	// Default and maximum number of results of list_packages
+	defaultPackages = 200
+	maxPackages     = 1000
+	// Maximum number of lines read_source returns at once
+	maxReadLines = 300
+	// Lines before a definition that read_source returns by default
+	contextLines = 5
+)
+
+func languageNames() []string {
+	names := make([]string, 0)
+	for _, indexer := range common.Indexers() {
+		names = append(names, string(indexer.Language()))
+	}
+	return names
+}
+
+func tools() []tool {
+	languages := languageNames()
+	return []tool{
+		{
+			Name: "search_symbols",
+			Description: "Find modules, classes, functions, methods and constants defined in the installed " +
+				"libraries by name. Returns one JSON object per line with the id, language, kind, name, path, " +
+				"line, package and version of each definition.",
+			InputSchema: schema{
+				Type: "object",
+				Properties: map[string]schema{
+					"query": {Type: "string", Description: "Terms that names contain or abbreviate (e.g. " +
+						"Session.request, requests.sessions or cfgLdr for ConfigLoader), with optional lang:, pkg:, " +
+						"version: and kind: qualifiers, -term to exclude names and /regex/ terms"},
+					"language": {Type: "string", Enum: languages},
+					"package":  {Type: "string", Description: "Part of the library name (e.g. lodash or guava)"},
+					"version":  {Type: "string", Description: "Library version, or a prefix of it (e.g. 4)"},
+					"kind": {Type: "string", Enum: []string{"module", "class", "interface", "enum", "record", "type",
+						"function", "method", "constant", "variable"}},
+					"exact": {Type: "boolean", Description: "Match the query as a SQL LIKE pattern instead"},
+					"regex": {Type: "boolean", Description: "Match the query as a regular expression (Go syntax, " +
+						"e.g. ^org\\.springframework\\..*Factory$) instead"},
+					"limit": {Type: "integer", Description: fmt.Sprintf("Maximum number of results (default %d, at most %d)",
+						defaultSymbols, maxSymbols)},
+				},
+				Required: []string{"query"},
+			},
+		},
+		{
+			Name: "read_source",
+			Description: "Read the source of an installed library file, with line numbers. Pass the id of a " +
+				"search_symbols result to read from its definition, or the path of an indexed file.",
+			InputSchema: schema{
+				Type: "object",
+				Properties: map[string]schema{
+					"id":         {Type: "integer", Description: "id of a search_symbols result"},
+					"path":       {Type: "string", Description: "Path of an indexed file"},
+					"start_line": {Type: "integer", Description: "First line to read (default: just before the definition)"},
+					"end_line": {Type: "integer", Description: fmt.Sprintf("Last line to read (at most %d lines are read at once)",
+						maxReadLines)},
+				},
+			},
+		},
+		{
+			Name: "list_packages",
+			Description: "List the installed library versions in the index, one per line with the language, " +
+				"package, version and number of definitions separated by tabs.",
+			InputSchema: schema{
+				Type: "object",
+				Properties: map[string]schema{
+					"language": {Type: "string", Enum: languages},
+					"package":  {Type: "string", Description: "Part of the library name"},
+					"limit": {Type: "integer", Description: fmt.Sprintf("Maximum number of results (default %d, at most %d)",
+						defaultPackages, maxPackages)},
+				},
+			},
+		},
+	}
+}
+
+// callTool runs a tool. Errors of the tool are returned as results.
+func (s *Server) callTool(params callToolParams) (any, error) {
+	var text string
+	var err error
+	switch params.Name {
+	case "search_symbols":
+		var args searchArgs
+		if err = decodeArguments(params.Arguments, &args); err == nil {
+			text, err = s.searchSymbols(args)
+		}
+	case "read_source":
+		var args readArgs
+		if err = decodeArguments(params.Arguments, &args); err == nil {
+			text, err = s.readSource(args)
+		}
+	case "list_packages":
+		var args listArgs
+		if err = decodeArguments(params.Arguments, &args); err == nil {
+			text, err = s.listPackages(args)
+		}
+	default:
+		return nil, jsonrpc.InvalidParams(fmt.Errorf("unknown tool: %s", params.Name))
+	}
+	if err != nil {
+		return errorResult(err), nil
+	}
+	return textResult(text), nil
+}
+
+func decodeArguments(arguments json.RawMessage, v any) error {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		return nil
+	}
+	err := json.Unmarshal(arguments, v)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// parseLanguage returns the language with a name, or "" for every language.
+func parseLanguage(name string) (common.Language, error) {
+	language := common.LanguageFromName(name)
+	if name != "" && language == "" {
+		return "", fmt.Errorf("unknown language: %s (expected one of %s)", name, strings.Join(languageNames(), ", "))
+	}
+	return language, nil
+}
+
+// limit returns the requested number of results, or a default.
+func limit(requested int, defaultLimit int, maxLimit int) int {
+	if requested <= 0 {
+		return defaultLimit
+	}
+	return min(requested, maxLimit)
+}
+
+type searchArgs struct {
+	Query    string `json:"query"`
+	Language string `json:"language"`
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Kind     string `json:"kind"`
+	Exact    bool   `json:"exact"`
+	Regex    bool   `json:"regex"`
+	Limit    int    `json:"limit"`
+}
+
+func (s *Server) searchSymbols(args searchArgs) (string, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return "", errors.New("query is required")
+	}
+	language, err := parseLanguage(args.Language)
+	if err != nil {
+		return "", err
+	}
+	kind := common.KindFromName(args.Kind)
+	if args.Kind != "" && kind < 0 {
+		return "", fmt.Errorf("unknown kind: %s", args.Kind)
+	}
+	filter := common.DocumentFilter{Language: language, Package: args.Package, Version: args.Version}
+	match := common.MatchQuery
+	if args.Regex {
+		match = common.MatchRegexp
+	} else if args.Exact {
+		match = common.MatchLike
+	}
+	docs, err := common.FindDocuments(s.db, filter, args.Query, match)
+	if err != nil {
+		return "", err
+	}
+	docs = slices.DeleteFunc(docs, func(doc *common.SearchDocument) bool {
+		return (args.Kind != "" && doc.Kind != kind) || (s.project != nil && !s.project.Includes(doc))
+	})
+	if len(docs) == 0 {
+		return "No results", nil
+	}
+	total := len(docs)
+	docs = docs[:min(len(docs), limit(args.Limit, defaultSymbols, maxSymbols))]
+	var b strings.Builder
+	err = common.WriteDocuments(&b, docs, common.FormatJSONL)
+	if err != nil {
+		return "", err
+	}
+	if total > len(docs) {
+		fmt.Fprintf(&b, "(%d more results, narrow the query or filter by language, package or kind)\n",
+			total-len(docs))
+	}
+	return b.String(), nil
+}
+
+type readArgs struct {
+	ID        int64  `json:"id"`
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// readSource returns lines of an indexed file. Only indexed files can be read,
+// so the tool can't read anything else on the machine.
+func (s *Server) readSource(args readArgs) (string, error) {
+	var doc *common.SearchDocument
+	var err error
+	switch {
+	case args.ID != 0:
+		doc, err = common.FindDocument(s.db, args.ID)
+	case args.Path != "":
+		doc, err = common.FindFileDocument(s.db, args.Path)
+	default:
+		return "", errors.New("id or path is required")
+	}
+	if err != nil {
+		return "", err
+	}
+	code, err := common.ReadFile(doc.Path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(code), "\n"), "\n")
+	// Read from just before the definition by default
+	start := args.StartLine
+	if start <= 0 {
+		start = max(doc.Line-contextLines, 1)
+	}
+	if start > len(lines) {
+		return "", fmt.Errorf("start_line %d is after the end of the file (%d lines)", start, len(lines))
+	}
+	end := args.EndLine
+	if end <= 0 || end >= start+maxReadLines {
+		end = start + maxReadLines - 1
+	}
+	end = min(end, len(lines))
+	if end < start {
+		return "", fmt.Errorf("end_line %d is before start_line %d", end, start)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", doc.Path)
+	if doc.Package != "" {
+		fmt.Fprintf(&b, "%s %s\n", doc.Package, doc.Version)
+	}
+	fmt.Fprintf(&b, "Lines %d-%d of %d\n\n", start, end, len(lines))
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&b, "%6d: %s\n", i, lines[i-1])
+	}
+	if end < len(lines) {
+		fmt.Fprintf(&b, "\n(use start_line %d to read more)\n", end+1)
+	}
+	return b.String(), nil
+}
+
+type listArgs struct {
+	Language string `json:"language"`
+	Package  string `json:"package"`
+	Limit    int    `json:"limit"`
+}
+
+func (s *Server) listPackages(args listArgs) (string, error) {
+	language, err := parseLanguage(args.Language)
+	if err != nil {
+		return "", err
+	}
+	packages, err := common.ListPackages(s.db, language)
+	if err != nil {
+		return "", err
+	}
+	packages = slices.DeleteFunc(packages, func(pkg common.PackageSummary) bool {
+		return pkg.Package == "" ||
+			!strings.Contains(strings.ToLower(pkg.Package), strings.ToLower(args.Package)) ||
+			(s.project != nil && !s.project.Uses(pkg.Language, pkg.Package, pkg.Version))
+	})
+	if len(packages) == 0 {
+		return "No packages", nil
+	}
+	total := len(packages)
+	packages = packages[:min(len(packages), limit(args.Limit, defaultPackages, maxPackages))]
+	var b strings.Builder
+	for _, pkg := range packages {
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%d\n", pkg.Language, pkg.Package, pkg.Version, pkg.Documents)
+	}
+	if total > len(packages) {
+		fmt.Fprintf(&b, "(%d more packages, filter by language or package)\n", total-len(packages))
+	}
+	return b.String(), nil
+}
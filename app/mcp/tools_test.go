@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func TestReadSource(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db, err := common.OpenDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// A module with a function at line 8
+	path := filepath.Join(t.TempDir(), "mod.py")
+	lines := make([]string, 0)
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, "line "+strconv.Itoa(i))
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+	err = common.IndexDocuments(db, []*common.SearchDocument{
+		{Language: "python", Kind: common.Module, Name: "mod", Path: path, Package: "mod", Version: "1.0"},
+		{Language: "python", Kind: common.Function, Name: "mod.run", Path: path, Line: 8, Package: "mod", Version: "1.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := common.FindDocuments(db, common.DocumentFilter{}, "mod.run", common.MatchLike)
+	if err != nil || len(docs) != 1 {
+		t.Fatal(docs, err)
+	}
+	server := NewServer(db, nil)
+	tests := []struct {
+		args     readArgs
+		expected string
+	}{
+		// From just before the definition
+		{readArgs{ID: docs[0].ID}, path + "\nmod 1.0\nLines 3-10 of 10\n\n"},
+		{readArgs{Path: path, StartLine: 2, EndLine: 3}, "Lines 2-3 of 10\n\n     2: line 2\n     3: line 3\n\n(use start_line 4 to read more)\n"},
+		{readArgs{Path: path, StartLine: 11}, "after the end of the file"},
+		{readArgs{Path: filepath.Join(filepath.Dir(path), "other.py")}, "file not indexed"},
+	}
+	for _, test := range tests {
+		text, err := server.readSource(test.args)
+		if err != nil {
+			text = err.Error()
+		}
+		if !strings.Contains(text, test.expected) {
+			t.Errorf("readSource(%+v) = %q, expected %q", test.args, text, test.expected)
+		}
+	}
+}
@@ -25,55 +25,146 @@ import (
 	"github.com/brandtg/rtfm/app/common"
 )
 
-func Index() error {
-	slog.Info("Indexing Python modules...")
-	// Connect to the database
-	db, err := common.OpenDB()
-	if err != nil {
-		return fmt.Errorf("error opening database: %w", err)
+const Language common.Language = "python"
+
+type Indexer struct{}
+
+func init() {
+	common.Register(Indexer{})
+}
+
+func (Indexer) Language() common.Language {
+	return Language
+}
+
+func (Indexer) CommentPrefix() string {
+	return "#"
+}
+
+func (Indexer) Lexer() string {
+	return "python"
+}
+
+// pythonRoot is a site-packages directory of a virtual environment, or the
+// standard library directory of an interpreter.
+type pythonRoot struct {
+	Venv   string
+	Stdlib bool
+	// distributions maps the files of site-packages to their distributions
+	distributions map[string]*distribution
+}
+
+// Roots returns the site-packages directories of virtual environments (including
+// those in the project, if it isn't in the home directory), and the standard
+// library directories of their interpreters.
+func (Indexer) Roots(opts common.IndexOptions) ([]*common.Root, error) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return nil, fmt.Errorf("home environment variable not set")
+	}
+	dirs := []string{home}
+	if opts.Project != nil && !strings.HasPrefix(opts.Project.Dir, home+string(os.PathSeparator)) {
+		dirs = append(dirs, opts.Project.Dir)
 	}
-	defer db.Close()
 	// Find virtual environment modules
-	venvs, err := findVirtualEnvironments()
-	if err != nil {
-		return fmt.Errorf("error finding virtual environments: %w", err)
+	venvs := make([]string, 0)
+	for _, dir := range dirs {
+		found, err := findVirtualEnvironments(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error finding virtual environments: %w", err)
+		}
+		venvs = append(venvs, found...)
 	}
-	// Find modules in each virtual environment
-	var modules []PythonModule
-	var documents []*common.SearchDocument
+	roots := make([]*common.Root, 0)
 	for _, venv := range venvs {
 		slog.Info("Found virtual environment", "venv", venv)
-		modules, err = findModules(venv)
-		if err != nil {
-			return fmt.Errorf("error finding modules in virtual environment %s: %w", venv, err)
+		sitePackagesDir, err := findSitePackagesDir(venv)
+		if err != nil || sitePackagesDir == "" {
+			continue
 		}
-		// Create a search document for each module
-		for _, module := range modules {
-			doc := &common.SearchDocument{
-				Language: common.Python,
-				Name:     module.Name,
-				Path:     module.Path,
-			}
-			documents = append(documents, doc)
+		roots = append(roots, &common.Root{
+			Path:     sitePackagesDir,
+			Packages: findDistributionNames(sitePackagesDir),
+			Source:   &pythonRoot{Venv: venv},
+		})
+	}
+	// Find the standard library of each interpreter
+	for _, interpreter := range findInterpreters(venvs) {
+		slog.Info("Found Python interpreter", "stdlib", interpreter.StdlibDir, "version", interpreter.Version)
+		roots = append(roots, &common.Root{
+			Path:    interpreter.StdlibDir,
+			Package: common.StandardLibrary,
+			Version: interpreter.Version,
+			Source:  &pythonRoot{Stdlib: true},
+		})
+	}
+	return roots, nil
+}
+
+func (Indexer) Files(root *common.Root) ([]string, error) {
+	source := root.Source.(*pythonRoot)
+	var modules []PythonModule
+	var err error
+	if source.Stdlib {
+		modules, err = findStdlibModules(root.Path)
+	} else {
+		modules, err = findModules(source.Venv, root.Path)
+		if err == nil {
+			source.distributions, err = findDistributions(root.Path)
 		}
 	}
-	// TODO Find standard library modules
-	// Index the modules
-	slog.Info("Total", "documents", len(documents))
-	err = common.IndexDocuments(db, documents)
 	if err != nil {
-		return fmt.Errorf("error indexing documents: %w", err)
+		return nil, fmt.Errorf("error finding modules in %s: %w", root.Path, err)
+	}
+	paths := make([]string, 0, len(modules))
+	for _, module := range modules {
+		paths = append(paths, module.Path)
 	}
-	return nil
+	return paths, nil
 }
 
-func findVirtualEnvironments() ([]string, error) {
-	home := os.Getenv("HOME")
-	if home == "" {
-		return nil, fmt.Errorf("home environment variable not set")
+// Parse creates a search document for the module and its symbols.
+func (Indexer) Parse(root *common.Root, path string, code []byte) ([]*common.SearchDocument, error) {
+	name := moduleNameFromPath(root.Path, path)
+	pkg, version := root.Package, root.Version
+	if dist, ok := root.Source.(*pythonRoot).distributions[path]; ok {
+		pkg, version = dist.Name, dist.Version
 	}
+	documents := []*common.SearchDocument{{
+		Language: Language,
+		Kind:     common.Module,
+		Name:     name,
+		Path:     path,
+		Package:  pkg,
+		Version:  version,
+		Root:     root.Path,
+	}}
+	for _, symbol := range parsePythonSymbols(name, string(code)) {
+		documents = append(documents, &common.SearchDocument{
+			Language: Language,
+			Kind:     symbol.Kind,
+			Name:     symbol.Name,
+			Path:     path,
+			Line:     symbol.Line,
+			Package:  pkg,
+			Version:  version,
+			Root:     root.Path,
+		})
+	}
+	return documents, nil
+}
+
+func findVirtualEnvironments(dir string) ([]string, error) {
 	venvs := make([]string, 0)
-	filepath.WalkDir(home, func(path string, d os.DirEntry, err error) error {
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		// Log errors (usually permissions) and skip what can't be read
+		if err != nil {
+			slog.Warn("Error walking directory", "path", path, "error", err)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
 		if !d.IsDir() {
 			name := filepath.Base(path)
 			if name == "pyvenv.cfg" {
@@ -127,52 +218,46 @@ type PythonModule struct {
 	SitePackagesDir string
 }
 
-func findModules(venv string) ([]PythonModule, error) {
-	// Find site-packages directory
-	sitePackagesDir, err := findSitePackagesDir(venv)
-	if err != nil {
-		slog.Error("Error finding sitePackagesDir", "sitePackagesDir", sitePackagesDir)
-		return nil, err
-	}
-	// Find init files (which identify a module)
-	initFiles := make([]string, 0)
-	err = filepath.Walk(sitePackagesDir,
-		func(path string, d os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !d.IsDir() && d.Name() == "__init__.py" {
-				initFiles = append(initFiles, path)
-			}
-			return nil
-		})
-	if err != nil {
-		return nil, err
+// isIdentifier reports whether a module or package name can be imported.
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if !isNameChar(r) || (i == 0 && !isNameStart(r)) {
+			return false
+		}
 	}
-	// Find the other python files in those modules
-	allFiles := make([]string, 0)
-	for _, initFile := range initFiles {
-		dir := filepath.Dir(initFile)
-		files, err := os.ReadDir(dir)
+	return name != ""
+}
+
+// findModules finds the modules in site-packages, including sub-packages
+// without an __init__.py (namespace packages) and top-level modules (e.g.
+// six.py).
+func findModules(venv string, sitePackagesDir string) ([]PythonModule, error) {
+	acc := make([]PythonModule, 0)
+	err := filepath.WalkDir(sitePackagesDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return nil, err
+			slog.Warn("Error walking directory", "path", path, "error", err)
+			return nil
 		}
-		for _, file := range files {
-			if strings.HasSuffix(file.Name(), ".py") {
-				allFiles = append(allFiles, filepath.Join(dir, file.Name()))
+		name := d.Name()
+		if d.IsDir() {
+			// Directories that can't be imported (e.g. requests-2.31.0.dist-info)
+			if path != sitePackagesDir && !isIdentifier(name) {
+				return fs.SkipDir
 			}
+			return nil
 		}
-	}
-	// Map to modules
-	acc := make([]PythonModule, 0)
-	for _, file := range allFiles {
-		name := moduleNameFromPath(sitePackagesDir, file)
-		acc = append(acc, PythonModule{
-			Venv:            venv,
-			Name:            name,
-			Path:            file,
-			SitePackagesDir: sitePackagesDir,
-		})
+		if strings.HasSuffix(name, ".py") && isIdentifier(strings.TrimSuffix(name, ".py")) {
+			acc = append(acc, PythonModule{
+				Venv:            venv,
+				Name:            moduleNameFromPath(sitePackagesDir, path),
+				Path:            path,
+				SitePackagesDir: sitePackagesDir,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return acc, nil
 }
@@ -0,0 +1,189 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// PythonInterpreter is an installed Python, identified by its standard
+// library directory (e.g. /usr/lib/python3.11).
+type PythonInterpreter struct {
+	StdlibDir string
+	Version   string
+}
+
+var (
+	stdlibDirRegex    = regexp.MustCompile(`^python(\d+\.\d+)$`)
+	pyenvVersionRegex = regexp.MustCompile(`^\d+\.\d+`)
+)
+
+var excludedStdlibDirs = map[string]bool{
+	"site-packages": true,
+	"dist-packages": true,
+	"lib-dynload":   true,
+	"__pycache__":   true,
+	"test":          true,
+	"tests":         true,
+	"idle_test":     true,
+}
+
+// parsePyvenvConfig reads the base interpreter's bin directory ("home") and
+// version from a virtual environment's pyvenv.cfg.
+func parsePyvenvConfig(venv string) (string, string, error) {
+	file, err := os.Open(filepath.Join(venv, "pyvenv.cfg"))
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	version := values["version"]
+	if version == "" {
+		// e.g. version_info = 3.11.4.final.0
+		parts := strings.Split(values["version_info"], ".")
+		version = strings.Join(parts[:min(len(parts), 3)], ".")
+	}
+	return values["home"], version, scanner.Err()
+}
+
+// stdlibVersion returns the major.minor version in the name of a standard
+// library directory (e.g. 3.11 for /usr/lib/python3.11), or "".
+func stdlibVersion(stdlibDir string) string {
+	match := stdlibDirRegex.FindStringSubmatch(filepath.Base(stdlibDir))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// findStdlibDirs finds the standard library directories under an
+// installation prefix (e.g. /usr/lib/python3.11 for /usr).
+func findStdlibDirs(prefix string) []string {
+	matches, _ := filepath.Glob(filepath.Join(prefix, "lib", "python3.*"))
+	acc := make([]string, 0)
+	for _, match := range matches {
+		if stdlibDirRegex.MatchString(filepath.Base(match)) && common.Exists(filepath.Join(match, "os.py")) {
+			acc = append(acc, match)
+		}
+	}
+	return acc
+}
+
+// findInterpreters finds the system interpreters, pyenv versions, and the base
+// interpreters of the given virtual environments.
+func findInterpreters(venvs []string) []PythonInterpreter {
+	interpreters := make(map[string]PythonInterpreter)
+	add := func(stdlibDir string, version string) {
+		// Default to the major.minor version in the directory name (before
+		// resolving symlinks, which can point to a directory named otherwise)
+		if version == "" {
+			version = stdlibVersion(stdlibDir)
+		}
+		if resolved, err := filepath.EvalSymlinks(stdlibDir); err == nil {
+			stdlibDir = resolved
+		}
+		// Keep the most precise version (e.g. 3.11.4 over 3.11)
+		if existing, ok := interpreters[stdlibDir]; !ok || len(version) > len(existing.Version) {
+			interpreters[stdlibDir] = PythonInterpreter{StdlibDir: stdlibDir, Version: version}
+		}
+	}
+	// System interpreters
+	for _, prefix := range []string{"/usr", "/usr/local"} {
+		for _, dir := range findStdlibDirs(prefix) {
+			add(dir, "")
+		}
+	}
+	// pyenv interpreters, named by version (e.g. ~/.pyenv/versions/3.11.4)
+	home, err := os.UserHomeDir()
+	if err == nil {
+		pyenvDir := filepath.Join(home, ".pyenv", "versions")
+		entries, _ := os.ReadDir(pyenvDir)
+		for _, entry := range entries {
+			version := ""
+			if pyenvVersionRegex.MatchString(entry.Name()) {
+				version = entry.Name()
+			}
+			for _, dir := range findStdlibDirs(filepath.Join(pyenvDir, entry.Name())) {
+				add(dir, version)
+			}
+		}
+	}
+	// Base interpreters of virtual environments, where home is the bin directory
+	for _, venv := range venvs {
+		binDir, version, err := parsePyvenvConfig(venv)
+		if err != nil || binDir == "" {
+			continue
+		}
+		for _, dir := range findStdlibDirs(filepath.Dir(binDir)) {
+			dirVersion := stdlibVersion(dir)
+			if version == dirVersion || strings.HasPrefix(version, dirVersion+".") {
+				add(dir, version)
+			}
+		}
+	}
+	acc := make([]PythonInterpreter, 0, len(interpreters))
+	for _, interpreter := range interpreters {
+		acc = append(acc, interpreter)
+	}
+	slices.SortFunc(acc, func(a, b PythonInterpreter) int {
+		return strings.Compare(a.StdlibDir, b.StdlibDir)
+	})
+	return acc
+}
+
+// findStdlibModules finds the modules of a standard library, skipping
+// installed packages, the test suite and compiled extensions.
+func findStdlibModules(stdlibDir string) ([]PythonModule, error) {
+	acc := make([]PythonModule, 0)
+	err := filepath.WalkDir(stdlibDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != stdlibDir {
+			// Directories that aren't importable (e.g. config-3.11-x86_64-linux-gnu)
+			name := d.Name()
+			if excludedStdlibDirs[name] || strings.Contains(name, "-") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".py") {
+			acc = append(acc, PythonModule{
+				Name: moduleNameFromPath(stdlibDir, path),
+				Path: path,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
@@ -0,0 +1,93 @@
+package python
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParsePyvenvConfig(t *testing.T) {
+	tests := []struct {
+		config  string
+		home    string
+		version string
+	}{
+		{"home = /usr/bin\nversion = 3.11.4\n", "/usr/bin", "3.11.4"},
+		{"home=/opt/python/bin\nversion_info = 3.12.1.final.0\n", "/opt/python/bin", "3.12.1"},
+		{"include-system-site-packages = false\n", "", ""},
+	}
+	for _, test := range tests {
+		venv := t.TempDir()
+		os.WriteFile(filepath.Join(venv, "pyvenv.cfg"), []byte(test.config), 0o644)
+		home, version, err := parsePyvenvConfig(venv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if home != test.home || version != test.version {
+			t.Errorf("%q: expected %q, %q, got %q, %q", test.config, test.home, test.version, home, version)
+		}
+	}
+	if _, _, err := parsePyvenvConfig(t.TempDir()); err == nil {
+		t.Errorf("expected an error without pyvenv.cfg")
+	}
+}
+
+func writeStdlib(t *testing.T, dir string) {
+	os.MkdirAll(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "os.py"), nil, 0o644)
+}
+
+func TestFindInterpreters(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	versions := filepath.Join(home, ".pyenv", "versions")
+	// A pyenv version
+	writeStdlib(t, filepath.Join(versions, "3.12.1", "lib", "python3.12"))
+	// A pyenv build whose standard library is a symlink to a directory that
+	// isn't named by its version
+	custom := filepath.Join(versions, "custom", "lib")
+	writeStdlib(t, filepath.Join(custom, "stdlib"))
+	os.Symlink(filepath.Join(custom, "stdlib"), filepath.Join(custom, "python3.11"))
+	// A virtual environment, whose base interpreter has two standard libraries
+	prefix := filepath.Join(home, "python")
+	writeStdlib(t, filepath.Join(prefix, "lib", "python3.10"))
+	writeStdlib(t, filepath.Join(prefix, "lib", "python3.9"))
+	venv := filepath.Join(home, "venv")
+	os.MkdirAll(venv, 0o755)
+	config := "home = " + filepath.Join(prefix, "bin") + "\nversion_info = 3.10.4.final.0\n"
+	os.WriteFile(filepath.Join(venv, "pyvenv.cfg"), []byte(config), 0o644)
+	interpreters := findInterpreters([]string{venv, filepath.Join(home, "missing")})
+	// System interpreters are found too
+	expected := []PythonInterpreter{
+		{StdlibDir: filepath.Join(versions, "3.12.1", "lib", "python3.12"), Version: "3.12.1"},
+		{StdlibDir: filepath.Join(custom, "stdlib"), Version: "3.11"},
+		{StdlibDir: filepath.Join(prefix, "lib", "python3.10"), Version: "3.10.4"},
+	}
+	for _, interpreter := range expected {
+		if !slices.Contains(interpreters, interpreter) {
+			t.Errorf("expected %+v in %+v", interpreter, interpreters)
+		}
+	}
+	for _, interpreter := range interpreters {
+		if interpreter.StdlibDir == filepath.Join(prefix, "lib", "python3.9") {
+			t.Errorf("unexpected interpreter for another version: %+v", interpreter)
+		}
+	}
+}
+
+func TestFindVirtualEnvironments(t *testing.T) {
+	dir := t.TempDir()
+	venv := filepath.Join(dir, "project", ".venv")
+	os.MkdirAll(venv, 0o755)
+	os.WriteFile(filepath.Join(venv, "pyvenv.cfg"), []byte("home = /usr/bin\n"), 0o644)
+	venvs, err := findVirtualEnvironments(dir)
+	if err != nil || !slices.Equal(venvs, []string{venv}) {
+		t.Errorf("unexpected virtual environments: %v (%v)", venvs, err)
+	}
+	// Directories that can't be read are skipped
+	venvs, err = findVirtualEnvironments(filepath.Join(dir, "missing"))
+	if err != nil || len(venvs) != 0 {
+		t.Errorf("unexpected virtual environments in a missing directory: %v (%v)", venvs, err)
+	}
+}
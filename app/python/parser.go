@@ -0,0 +1,529 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+type tokenKind int
+
+const (
+	tokenName tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// logicalLine is a statement (or several, separated by semicolons), which can
+// span several physical lines inside brackets or after a backslash.
+type logicalLine struct {
+	indent int
+	tokens []token
+}
+
+// Operators are matched longest first
+var pythonOperators = []string{
+	"**=", "//=", ">>=", "<<=", "...",
+	"->", ":=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "@=", "==", "!=", "<=", ">=", "**", "//", "<<", ">>",
+}
+
+// isStringPrefix reports whether a name is a string prefix (e.g. the rb in
+// rb"...").
+func isStringPrefix(name string) bool {
+	if len(name) > 2 {
+		return false
+	}
+	for _, r := range strings.ToLower(name) {
+		if !strings.ContainsRune("rbuft", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenizer splits Python code into logical lines of tokens, skipping comments
+// and blank lines.
+type tokenizer struct {
+	code  string
+	pos   int
+	line  int
+	depth int
+	lines []logicalLine
+}
+
+func tokenize(code string) []logicalLine {
+	t := &tokenizer{code: code, line: 1}
+	atLineStart := true
+	for t.pos < len(t.code) {
+		if atLineStart && t.depth == 0 {
+			if !t.startLine() {
+				continue
+			}
+			atLineStart = false
+		}
+		c := t.code[t.pos]
+		switch {
+		case c == '\n':
+			t.pos++
+			t.line++
+			atLineStart = t.depth == 0
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			t.pos++
+		case c == '#':
+			t.skipComment()
+		case c == '\\' && strings.HasPrefix(t.code[t.pos+1:], "\n"):
+			// Line continuation
+			t.pos += 2
+			t.line++
+		case c == '\\' && strings.HasPrefix(t.code[t.pos+1:], "\r\n"):
+			t.pos += 3
+			t.line++
+		case c == '"' || c == '\'':
+			t.readString(t.pos)
+		case c >= '0' && c <= '9' || c == '.' && t.pos+1 < len(t.code) && t.code[t.pos+1] >= '0' && t.code[t.pos+1] <= '9':
+			t.readNumber()
+		default:
+			r, size := utf8.DecodeRuneInString(t.code[t.pos:])
+			if isNameStart(r) {
+				t.readName()
+			} else {
+				t.readOperator(size)
+			}
+		}
+	}
+	return t.lines
+}
+
+// startLine measures the indentation of the next line, and starts a logical
+// line unless it is blank.
+func (t *tokenizer) startLine() bool {
+	column := 0
+	for t.pos < len(t.code) {
+		switch t.code[t.pos] {
+		case ' ':
+			column++
+		case '\t':
+			column = (column/8 + 1) * 8
+		case '\f':
+			column = 0
+		default:
+			goto measured
+		}
+		t.pos++
+	}
+measured:
+	if t.pos >= len(t.code) {
+		return false
+	}
+	switch t.code[t.pos] {
+	case '#':
+		t.skipComment()
+		return false
+	case '\r':
+		t.pos++
+		return false
+	case '\n':
+		t.pos++
+		t.line++
+		return false
+	}
+	t.lines = append(t.lines, logicalLine{indent: column})
+	return true
+}
+
+func (t *tokenizer) skipComment() {
+	end := strings.IndexByte(t.code[t.pos:], '\n')
+	if end < 0 {
+		t.pos = len(t.code)
+	} else {
+		t.pos += end
+	}
+}
+
+func (t *tokenizer) emit(kind tokenKind, text string, line int) {
+	if len(t.lines) == 0 {
+		t.lines = append(t.lines, logicalLine{})
+	}
+	current := &t.lines[len(t.lines)-1]
+	current.tokens = append(current.tokens, token{kind: kind, text: text, line: line})
+}
+
+// readString reads a string literal, whose prefix (if any) starts at start.
+func (t *tokenizer) readString(start int) {
+	line := t.line
+	quote := t.code[t.pos]
+	delimiter := string(quote)
+	if strings.HasPrefix(t.code[t.pos:], strings.Repeat(delimiter, 3)) {
+		delimiter = strings.Repeat(delimiter, 3)
+	}
+	t.pos += len(delimiter)
+	for t.pos < len(t.code) {
+		c := t.code[t.pos]
+		if c == '\\' {
+			// Escapes (even in raw strings, a backslash keeps the quote)
+			if t.pos+1 < len(t.code) && t.code[t.pos+1] == '\n' {
+				t.line++
+			}
+			t.pos += 2
+			continue
+		}
+		if strings.HasPrefix(t.code[t.pos:], delimiter) {
+			t.pos += len(delimiter)
+			break
+		}
+		if c == '\n' {
+			if len(delimiter) == 1 {
+				// Unterminated string
+				break
+			}
+			t.line++
+		}
+		t.pos++
+	}
+	t.pos = min(t.pos, len(t.code))
+	t.emit(tokenString, t.code[start:t.pos], line)
+}
+
+func (t *tokenizer) readNumber() {
+	start := t.pos
+	for t.pos < len(t.code) {
+		c := t.code[t.pos]
+		exponent := (c == '+' || c == '-') && strings.ContainsRune("eE", rune(t.code[t.pos-1])) &&
+			!strings.HasPrefix(strings.ToLower(t.code[start:]), "0x")
+		if !(c == '_' || c == '.' || exponent || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			break
+		}
+		t.pos++
+	}
+	t.emit(tokenNumber, t.code[start:t.pos], t.line)
+}
+
+func (t *tokenizer) readName() {
+	start := t.pos
+	for t.pos < len(t.code) {
+		r, size := utf8.DecodeRuneInString(t.code[t.pos:])
+		if !isNameChar(r) {
+			break
+		}
+		t.pos += size
+	}
+	// A string with a prefix (e.g. f"...")
+	if t.pos < len(t.code) && (t.code[t.pos] == '"' || t.code[t.pos] == '\'') && isStringPrefix(t.code[start:t.pos]) {
+		t.readString(start)
+		return
+	}
+	t.emit(tokenName, t.code[start:t.pos], t.line)
+}
+
+func (t *tokenizer) readOperator(size int) {
+	for _, operator := range pythonOperators {
+		if strings.HasPrefix(t.code[t.pos:], operator) {
+			t.emit(tokenOperator, operator, t.line)
+			t.pos += len(operator)
+			return
+		}
+	}
+	operator := t.code[t.pos : t.pos+size]
+	switch operator {
+	case "(", "[", "{":
+		t.depth++
+	case ")", "]", "}":
+		t.depth = max(t.depth-1, 0)
+	}
+	t.emit(tokenOperator, operator, t.line)
+	t.pos += size
+}
+
+// stringValue returns the contents of a string literal, without its prefix and
+// quotes (escapes are kept).
+func stringValue(literal string) string {
+	literal = strings.TrimLeft(literal, "rbuftRBUFT")
+	for _, delimiter := range []string{`"""`, `'''`, `"`, `'`} {
+		if strings.HasPrefix(literal, delimiter) {
+			literal = strings.TrimPrefix(literal, delimiter)
+			return strings.TrimSuffix(literal, delimiter)
+		}
+	}
+	return literal
+}
+
+// pythonScope is a class or function body, identified by its indentation.
+type pythonScope struct {
+	indent int
+	name   string
+	kind   common.Kind
+}
+
+// isConstantName reports whether a name is UPPER_CASE, by convention a
+// constant.
+func isConstantName(name string) bool {
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// exportKind guesses the kind of a name exported by __all__ that isn't defined
+// in the module (usually imported from a submodule), by its naming convention.
+func exportKind(name string) common.Kind {
+	switch {
+	case isConstantName(name):
+		return common.Constant
+	case unicode.IsUpper([]rune(name)[0]):
+		return common.Class
+	default:
+		return common.Function
+	}
+}
+
+// splitStatements splits a logical line at semicolons.
+func splitStatements(tokens []token) [][]token {
+	statements := make([][]token, 0)
+	start := 0
+	for i, tok := range tokens {
+		if tok.kind == tokenOperator && tok.text == ";" {
+			statements = append(statements, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(statements, tokens[start:])
+}
+
+// assignmentTargets returns the names a statement assigns (e.g. A and B in
+// A = B = 1, or A, B = 1, 2), and whether it is annotated as a type alias.
+// Attributes and items (a.b = 1, a[0] = 1) aren't names.
+func assignmentTargets(statement []token) ([]token, bool) {
+	targets := make([]token, 0)
+	typeAlias := false
+	start := 0
+	depth := 0
+	for i, tok := range statement {
+		if tok.kind != tokenOperator {
+			continue
+		}
+		switch tok.text {
+		case "(", "[", "{":
+			depth++
+			continue
+		case ")", "]", "}":
+			depth--
+			continue
+		case "=":
+		default:
+			continue
+		}
+		if depth != 0 {
+			continue
+		}
+		target := statement[start:i]
+		start = i + 1
+		// Annotated assignments (e.g. X: Final = 1)
+		if len(target) >= 3 && target[0].kind == tokenName && target[1].text == ":" {
+			typeAlias = target[len(target)-1].text == "TypeAlias"
+			target = target[:1]
+		}
+		names := make([]token, 0)
+		for j, name := range target {
+			next := ""
+			if j+1 < len(target) {
+				next = target[j+1].text
+			}
+			if name.kind == tokenName && next != "." && next != "[" && next != "(" {
+				names = append(names, name)
+			} else if name.kind != tokenOperator || !strings.Contains("(),[]", name.text) {
+				names = nil
+				break
+			}
+		}
+		targets = append(targets, names...)
+	}
+	return targets, typeAlias
+}
+
+// importedNames returns the names an import statement binds, with their
+// lines.
+func importedNames(statement []token) []token {
+	names := make([]token, 0)
+	if len(statement) == 0 || statement[0].kind != tokenName {
+		return names
+	}
+	switch statement[0].text {
+	case "from":
+		// from module import a, b as c, (d, e)
+		for i, tok := range statement {
+			if tok.kind == tokenName && tok.text == "import" {
+				statement = statement[i+1:]
+				break
+			}
+		}
+	case "import":
+		// import a.b, c as d (binds a and d)
+		statement = statement[1:]
+	default:
+		return names
+	}
+	for i := 0; i < len(statement); i++ {
+		tok := statement[i]
+		if tok.kind != tokenName {
+			continue
+		}
+		if i+2 < len(statement) && statement[i+1].text == "as" {
+			names = append(names, statement[i+2])
+			i += 2
+		} else {
+			names = append(names, tok)
+		}
+		// Skip the rest of a dotted name
+		for i+2 < len(statement) && statement[i+1].text == "." {
+			i += 2
+		}
+	}
+	return names
+}
+
+// parsePythonSymbols finds the classes, functions, methods and constants
+// defined in a module, and the names its __all__ exports.
+func parsePythonSymbols(moduleName string, code string) []common.Symbol {
+	symbols := make([]common.Symbol, 0)
+	defined := make(map[string]bool)
+	// Module-level names, with the line they are bound on
+	bound := make(map[string]int)
+	imported := make(map[string]int)
+	exports := make([]token, 0)
+	scopes := make([]pythonScope, 0)
+	add := func(name string, kind common.Kind, line int) {
+		// Qualify the name with the enclosing scopes (e.g. requests.sessions.Session.request)
+		qualified := moduleName
+		for _, scope := range scopes {
+			qualified += "." + scope.name
+		}
+		qualified += "." + name
+		if !defined[qualified] {
+			defined[qualified] = true
+			symbols = append(symbols, common.Symbol{Name: qualified, Kind: kind, Line: line})
+		}
+	}
+	for _, line := range tokenize(code) {
+		// Close the scopes that this line is not nested in
+		for len(scopes) > 0 && scopes[len(scopes)-1].indent >= line.indent {
+			scopes = scopes[:len(scopes)-1]
+		}
+		for _, statement := range splitStatements(line.tokens) {
+			if len(statement) == 0 {
+				continue
+			}
+			first := statement[0]
+			// Classes and functions
+			if first.text == "async" && len(statement) > 1 && statement[1].text == "def" {
+				statement = statement[1:]
+				first = statement[0]
+			}
+			if first.kind == tokenName && (first.text == "def" || first.text == "class") &&
+				len(statement) > 1 && statement[1].kind == tokenName {
+				kind := common.Function
+				if first.text == "class" {
+					kind = common.Class
+				} else if len(scopes) > 0 && scopes[len(scopes)-1].kind == common.Class {
+					kind = common.Method
+				}
+				name := statement[1].text
+				add(name, kind, first.line)
+				if len(scopes) == 0 {
+					bound[name] = first.line
+				}
+				scopes = append(scopes, pythonScope{indent: line.indent, name: name, kind: kind})
+				break
+			}
+			// Function bodies only have local names
+			if len(scopes) > 0 && scopes[len(scopes)-1].kind != common.Class {
+				continue
+			}
+			// Type aliases (type X = ...)
+			if first.text == "type" && len(statement) > 2 && statement[1].kind == tokenName &&
+				(statement[2].text == "=" || statement[2].text == "[") {
+				add(statement[1].text, common.Type, first.line)
+				continue
+			}
+			// Names exported by __all__ (assigned, extended or appended to)
+			if first.text == "__all__" && len(scopes) == 0 {
+				for _, tok := range statement[1:] {
+					if tok.kind == tokenString {
+						exports = append(exports, token{kind: tokenName, text: stringValue(tok.text), line: tok.line})
+					}
+				}
+				continue
+			}
+			if len(scopes) == 0 {
+				for _, name := range importedNames(statement) {
+					if _, ok := imported[name.text]; !ok {
+						imported[name.text] = name.line
+					}
+				}
+			}
+			// Constants
+			targets, typeAlias := assignmentTargets(statement)
+			for _, target := range targets {
+				if typeAlias {
+					add(target.text, common.Type, target.line)
+				} else if isConstantName(target.text) {
+					add(target.text, common.Constant, target.line)
+				}
+				if _, ok := bound[target.text]; !ok && len(scopes) == 0 {
+					bound[target.text] = target.line
+				}
+			}
+		}
+	}
+	// Exported names that aren't classes, functions or constants of the module
+	scopes = scopes[:0]
+	for _, export := range exports {
+		name := export.text
+		if name == "" || !isNameStart([]rune(name)[0]) || defined[moduleName+"."+name] {
+			continue
+		}
+		if line, ok := bound[name]; ok {
+			add(name, common.Variable, line)
+		} else if line, ok := imported[name]; ok {
+			add(name, exportKind(name), line)
+		} else {
+			add(name, exportKind(name), export.line)
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,92 @@
+package python
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testModule = `"""Sessions.
+
+def not_a_function():
+"""
+from .models import Request, Response as Reply
+from . import (
+    adapters,
+    hooks as _hooks,
+)
+import os.path
+
+__all__ = ["Session", "Request", "Reply", "session",
+           "adapters"]
+__all__ += ['extra']
+
+DEFAULT_TIMEOUT: float = 30.0
+MAX_RETRIES = RETRIES = \
+    3
+ENV = {
+    "KEY": "value",
+}
+JsonDict: TypeAlias = dict
+type Headers = dict[str, str]
+CACHE[0] = None
+a, B = 1, 2
+
+
+@decorator(name="x")
+class Session(SessionRedirectMixin, metaclass=Meta):
+    TIMEOUT = 10
+    doc = '''
+    def also_not_a_function(self):
+    '''
+
+    def __init__(self, headers={"def": 1}):
+        LOCAL = 1
+
+        def inner():
+            pass
+
+    async def request(self, method, url,
+                      params=None):  # def commented(): pass
+        return r"\"" if method else f"{url}"
+
+    class Adapter: pass
+
+    def close(self): pass
+
+
+def session():
+    return Session()
+`
+
+func TestParsePythonSymbols(t *testing.T) {
+	var b strings.Builder
+	for _, symbol := range parsePythonSymbols("requests.sessions", testModule) {
+		fmt.Fprintf(&b, "%d %d %s\n", symbol.Line, symbol.Kind, symbol.Name)
+	}
+	expected := strings.Join([]string{
+		"16 8 requests.sessions.DEFAULT_TIMEOUT",
+		"17 8 requests.sessions.MAX_RETRIES",
+		"17 8 requests.sessions.RETRIES",
+		"19 8 requests.sessions.ENV",
+		"22 5 requests.sessions.JsonDict",
+		"23 5 requests.sessions.Headers",
+		"25 8 requests.sessions.B",
+		"29 1 requests.sessions.Session",
+		"30 8 requests.sessions.Session.TIMEOUT",
+		"35 7 requests.sessions.Session.__init__",
+		"38 6 requests.sessions.Session.__init__.inner",
+		"41 7 requests.sessions.Session.request",
+		"45 1 requests.sessions.Session.Adapter",
+		"47 7 requests.sessions.Session.close",
+		"50 6 requests.sessions.session",
+		// Re-exported by __all__
+		"5 1 requests.sessions.Request",
+		"5 1 requests.sessions.Reply",
+		"7 6 requests.sessions.adapters",
+		"14 6 requests.sessions.extra",
+	}, "\n") + "\n"
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// distribution is an installed package (e.g. requests 2.31.0), described by
+// the METADATA file in its .dist-info directory.
+type distribution struct {
+	Name    string
+	Version string
+}
+
+var distributionNameRegex = regexp.MustCompile(`[-_.]+`)
+
+// normalizeDistributionName normalizes a name as pip does (PEP 503), so that
+// e.g. Typing_Extensions and typing-extensions match.
+func normalizeDistributionName(name string) string {
+	return distributionNameRegex.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+}
+
+func parseDistributionMetadata(r io.Reader) *distribution {
+	dist := &distribution{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// The headers end at the first blank line
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Name":
+			dist.Name = normalizeDistributionName(value)
+		case "Version":
+			dist.Version = strings.TrimSpace(value)
+		}
+	}
+	return dist
+}
+
+// findDistributions maps the files listed in the RECORD of each distribution
+// in site-packages to the distribution.
+func findDistributions(sitePackagesDir string) (map[string]*distribution, error) {
+	distInfoDirs, err := filepath.Glob(filepath.Join(sitePackagesDir, "*.dist-info"))
+	if err != nil {
+		return nil, err
+	}
+	acc := make(map[string]*distribution)
+	for _, distInfoDir := range distInfoDirs {
+		metadata, err := os.Open(filepath.Join(distInfoDir, "METADATA"))
+		if err != nil {
+			continue
+		}
+		dist := parseDistributionMetadata(metadata)
+		metadata.Close()
+		record, err := os.Open(filepath.Join(distInfoDir, "RECORD"))
+		if err != nil || dist.Name == "" {
+			continue
+		}
+		// Each row is path,hash,size, with the path relative to site-packages
+		reader := csv.NewReader(record)
+		reader.FieldsPerRecord = -1
+		for {
+			row, err := reader.Read()
+			if err != nil {
+				break
+			}
+			if len(row) > 0 && strings.HasSuffix(row[0], ".py") {
+				acc[filepath.Join(sitePackagesDir, filepath.FromSlash(row[0]))] = dist
+			}
+		}
+		record.Close()
+	}
+	return acc, nil
+}
+
+// findDistributionNames returns the names of the distributions in
+// site-packages, from the names of their .dist-info directories (e.g.
+// typing_extensions-4.12.2.dist-info).
+func findDistributionNames(sitePackagesDir string) []string {
+	distInfoDirs, _ := filepath.Glob(filepath.Join(sitePackagesDir, "*.dist-info"))
+	acc := make([]string, 0, len(distInfoDirs))
+	for _, distInfoDir := range distInfoDirs {
+		name, _, _ := strings.Cut(filepath.Base(distInfoDir), "-")
+		acc = append(acc, normalizeDistributionName(name))
+	}
+	return acc
+}
+
+// Dependencies reads the package versions from the project's poetry.lock or
+// uv.lock, or the requirements in requirements.txt (with a version only if
+// pinned with ==).
+func (Indexer) Dependencies(projectDir string) ([]common.Dependency, error) {
+	acc := make([]common.Dependency, 0)
+	for _, name := range []string{"poetry.lock", "uv.lock"} {
+		file, err := os.Open(filepath.Join(projectDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dependencies, err := parseLockPackages(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", name, err)
+		}
+		acc = append(acc, dependencies...)
+	}
+	requirementsFile := filepath.Join(projectDir, "requirements.txt")
+	if len(acc) == 0 && common.Exists(requirementsFile) {
+		dependencies, err := parseRequirementsFile(requirementsFile, map[string]bool{})
+		if err != nil {
+			return nil, fmt.Errorf("error parsing requirements.txt: %w", err)
+		}
+		acc = append(acc, dependencies...)
+	}
+	return acc, nil
+}
+
+var tomlStringRegex = regexp.MustCompile(`^(name|version)\s*=\s*"([^"]*)"`)
+
+// parseLockPackages reads the name and version of each [[package]] table of a
+// poetry.lock or uv.lock file.
+func parseLockPackages(r io.Reader) ([]common.Dependency, error) {
+	acc := make([]common.Dependency, 0)
+	var current *common.Dependency
+	flush := func() {
+		if current != nil && current.Package != "" {
+			acc = append(acc, *current)
+		}
+		current = nil
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			flush()
+			if line == "[[package]]" {
+				current = &common.Dependency{Language: Language}
+			}
+			continue
+		}
+		// Keys of the package table itself (not of its subtables)
+		if current == nil {
+			continue
+		}
+		if match := tomlStringRegex.FindStringSubmatch(line); match != nil {
+			if match[1] == "name" {
+				current.Package = normalizeDistributionName(match[2])
+			} else {
+				current.Version = match[2]
+			}
+		}
+	}
+	flush()
+	return acc, scanner.Err()
+}
+
+var requirementRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*(?:==\s*([^\s;,]+))?`)
+
+// parseRequirementsFile reads a pip requirements file, and the files it
+// includes with -r.
+func parseRequirementsFile(path string, visited map[string]bool) ([]common.Dependency, error) {
+	if visited[path] {
+		return nil, nil
+	}
+	visited[path] = true
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	acc := make([]common.Dependency, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if include, ok := strings.CutPrefix(line, "-r"); ok {
+			include = filepath.Join(filepath.Dir(path), strings.TrimSpace(include))
+			dependencies, err := parseRequirementsFile(include, visited)
+			if err != nil {
+				return nil, err
+			}
+			acc = append(acc, dependencies...)
+			continue
+		}
+		// Skip other options (e.g. --index-url or -e)
+		if strings.HasPrefix(line, "-") {
+			continue
+		}
+		match := requirementRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		version := match[2]
+		if strings.Contains(version, "*") {
+			version = ""
+		}
+		acc = append(acc, common.Dependency{
+			Language: Language,
+			Package:  normalizeDistributionName(match[1]),
+			Version:  version,
+		})
+	}
+	return acc, scanner.Err()
+}
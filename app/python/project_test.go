@@ -0,0 +1,92 @@
+package python
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func formatDependencies(dependencies []common.Dependency) []string {
+	acc := make([]string, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		acc = append(acc, dependency.Package+"@"+dependency.Version)
+	}
+	return acc
+}
+
+func TestParseLockPackages(t *testing.T) {
+	tests := []struct {
+		name     string
+		lock     string
+		expected []string
+	}{
+		{"poetry.lock", `
+[[package]]
+name = "Typing_Extensions"
+version = "4.12.2"
+description = "Backported and Experimental Type Hints"
+
+[package.extras]
+name = "not a package"
+
+[[package]]
+name = "requests"
+version = "2.31.0"
+
+[metadata]
+lock-version = "2.0"
+`, []string{"typing-extensions@4.12.2", "requests@2.31.0"}},
+		{"uv.lock", `
+version = 1
+
+[[package]]
+name = "idna"
+version = "3.7"
+source = { registry = "https://pypi.org/simple" }
+dependencies = [
+    { name = "not-a-package" },
+]
+`, []string{"idna@3.7"}},
+	}
+	for _, test := range tests {
+		dependencies, err := parseLockPackages(strings.NewReader(test.lock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := formatDependencies(dependencies); !slices.Equal(actual, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestParseRequirementsFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"requirements.txt": `# Pinned
+requests==2.31.0  # comment
+Flask[async] == 3.0.0 ; python_version >= "3.8"
+numpy>=1.26
+django==4.*
+--index-url https://example.com/simple
+-e ./local
+-r requirements-dev.txt
+-r requirements.txt
+`,
+		"requirements-dev.txt": "pytest==8.0.0\n",
+	}
+	for name, body := range files {
+		os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644)
+	}
+	dependencies, err := parseRequirementsFile(filepath.Join(dir, "requirements.txt"), map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"requests@2.31.0", "flask@3.0.0", "numpy@", "django@", "pytest@8.0.0"}
+	if actual := formatDependencies(dependencies); !slices.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tui
+
+import (
+	"unicode/utf8"
+)
+
+// Names of the keys that aren't a printable character
+const (
+	keyEnter      = "enter"
+	keyEscape     = "esc"
+	keyBackspace  = "backspace"
+	keyTab        = "tab"
+	keyBackTab    = "backtab"
+	keyUp         = "up"
+	keyDown       = "down"
+	keyLeft       = "left"
+	keyRight      = "right"
+	keyShiftUp    = "shift-up"
+	keyShiftDown  = "shift-down"
+	keyPageUp     = "pgup"
+	keyPageDown   = "pgdn"
+	keyHome       = "home"
+	keyEnd        = "end"
+	keyClearQuery = "ctrl-u"
+	keyDeleteWord = "ctrl-w"
+	keyPreviewUp  = "ctrl-b"
+	keyPreviewDn  = "ctrl-f"
+)
+
+// Escape sequences sent by common terminals
+var escapeKeys = map[string]string{
+	"\x1b[A":    keyUp,
+	"\x1b[B":    keyDown,
+	"\x1b[C":    keyRight,
+	"\x1b[D":    keyLeft,
+	"\x1bOA":    keyUp,
+	"\x1bOB":    keyDown,
+	"\x1bOC":    keyRight,
+	"\x1bOD":    keyLeft,
+	"\x1b[1;2A": keyShiftUp,
+	"\x1b[1;2B": keyShiftDown,
+	"\x1b[5~":   keyPageUp,
+	"\x1b[6~":   keyPageDown,
+	"\x1b[H":    keyHome,
+	"\x1b[F":    keyEnd,
+	"\x1bOH":    keyHome,
+	"\x1bOF":    keyEnd,
+	"\x1b[1~":   keyHome,
+	"\x1b[4~":   keyEnd,
+	"\x1b[Z":    keyBackTab,
+}
+
+// Control characters, with ctrl-n/p/j/k moving like fzf
+var controlKeys = map[byte]string{
+	'\r':   keyEnter,
+	'\n':   keyDown,
+	'\t':   keyTab,
+	0x7f:   keyBackspace,
+	0x08:   keyBackspace,
+	0x03:   keyEscape, // ctrl-c
+	0x07:   keyEscape, // ctrl-g
+	0x0e:   keyDown,   // ctrl-n
+	0x10:   keyUp,     // ctrl-p
+	0x0b:   keyUp,     // ctrl-k
+	0x15:   keyClearQuery,
+	0x17:   keyDeleteWord,
+	0x02:   keyPreviewUp,
+	0x06:   keyPreviewDn,
+	'\x1b': keyEscape,
+}
+
+// escapeEnd returns the end of the escape sequence that starts at i: a CSI
+// sequence ends with a byte in @-~, and other sequences are two bytes long
+// (or three for SS3, e.g. \x1bOA).
+func escapeEnd(s string, i int) int {
+	if i+1 >= len(s) {
+		return len(s)
+	}
+	switch s[i+1] {
+	case '[':
+		for j := i + 2; j < len(s); j++ {
+			if s[j] >= 0x40 && s[j] <= 0x7e {
+				return j + 1
+			}
+		}
+		return len(s)
+	case 'O':
+		return min(i+3, len(s))
+	}
+	return i + 2
+}
+
+// parseKeys splits the bytes of one read from the terminal into keys. A lone
+// escape byte is the escape key, since terminals write each escape sequence
+// at once.
+func parseKeys(data []byte) []string {
+	s := string(data)
+	acc := make([]string, 0)
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' && i+1 < len(s) {
+			j := escapeEnd(s, i)
+			if key, ok := escapeKeys[s[i:j]]; ok {
+				acc = append(acc, key)
+			}
+			i = j
+			continue
+		}
+		if key, ok := controlKeys[s[i]]; ok {
+			acc = append(acc, key)
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r >= 0x20 && r != utf8.RuneError {
+			acc = append(acc, string(r))
+		}
+		i += size
+	}
+	return acc
+}
+
+// isPrintable reports whether a key is a character to add to the query.
+func isPrintable(key string) bool {
+	return utf8.RuneCountInString(key) == 1
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Files larger than this are previewed without highlighting
+const maxHighlightSize = 1 << 20
+
+// Number of highlighted files kept for the preview
+const maxPreviews = 64
+
+const (
+	reverse   = "\x1b[7m"
+	dim       = "\x1b[2m"
+	bold      = "\x1b[1m"
+	resetText = "\x1b[0m"
+	clearLine = "\x1b[K"
+)
+
+// truncate cuts a line that may contain ANSI escape sequences to width
+// visible characters, and returns it with its visible width.
+func truncate(s string, width int) (string, int) {
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			j := escapeEnd(s, i)
+			b.WriteString(s[i:j])
+			i = j
+			continue
+		}
+		if visible == width {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r < 0x20 {
+			r = ' '
+		}
+		b.WriteRune(r)
+		visible++
+		i += size
+	}
+	return b.String(), visible
+}
+
+// pad truncates or pads a line without escape sequences to width characters.
+func pad(s string, width int) string {
+	s, visible := truncate(s, width)
+	return s + strings.Repeat(" ", width-visible)
+}
+
+// splitLines splits highlighted code into lines, starting each line with the
+// styles that are still active from the previous one (e.g. in a multi-line
+// comment).
+func splitLines(text string) []string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	state := ""
+	for i, line := range lines {
+		lines[i] = state + line
+		for j := 0; j < len(line); {
+			if line[j] != '\x1b' {
+				j++
+				continue
+			}
+			k := escapeEnd(line, j)
+			if seq := line[j:k]; seq == resetText || seq == "\x1b[m" {
+				state = ""
+			} else if strings.HasSuffix(seq, "m") {
+				state += seq
+			}
+			j = k
+		}
+	}
+	return lines
+}
+
+// filePreview is the lines of a file, after header lines with its path (if it
+// was highlighted).
+type filePreview struct {
+	lines  []string
+	header int
+}
+
+// highlight reads a file and returns its lines, highlighted like the pager
+// (with the path as a comment on the first line). Large files, and files that
+// can't be highlighted, are shown as they are.
+func highlight(doc *common.SearchDocument) filePreview {
+	info, err := common.StatFile(doc.Path)
+	if err != nil {
+		return filePreview{lines: []string{fmt.Sprintf("Error reading %s: %v", doc.Path, err)}}
+	}
+	data, err := common.ReadFile(doc.Path)
+	if err != nil {
+		return filePreview{lines: []string{fmt.Sprintf("Error reading %s: %v", doc.Path, err)}}
+	}
+	code := strings.ReplaceAll(string(data), "\t", "    ")
+	if info.Size() > maxHighlightSize {
+		return filePreview{lines: strings.Split(code, "\n")}
+	}
+	highlighted, err := common.HighlightCode(code, doc.Language, doc.Path, common.TerminalFormatter)
+	if err != nil {
+		return filePreview{lines: strings.Split(code, "\n")}
+	}
+	return filePreview{lines: splitLines(highlighted), header: common.HighlightHeaderLines}
+}
+
+// preview returns the lines of a document's file.
+func (b *Browser) preview(doc *common.SearchDocument) filePreview {
+	if preview, ok := b.previews[doc.Path]; ok {
+		return preview
+	}
+	if len(b.previews) >= maxPreviews {
+		clear(b.previews)
+	}
+	preview := highlight(doc)
+	b.previews[doc.Path] = preview
+	return preview
+}
+
+// docLabel is the line of a document in the results list.
+func docLabel(doc *common.SearchDocument) string {
+	label := fmt.Sprintf("%-9s %s", common.NameFromKind(doc.Kind), doc.Name)
+	if doc.Package != "" {
+		label += "  " + doc.Package
+		if doc.Version != "" {
+			label += "@" + doc.Version
+		}
+	}
+	return label
+}
+
+// render draws the language tabs, the query, the results next to a preview
+// of the selected one, and a status line.
+func (b *Browser) render() string {
+	var s strings.Builder
+	row := func(y int, text string) {
+		fmt.Fprintf(&s, "\x1b[%d;1H%s%s%s", y+1, text, resetText, clearLine)
+	}
+	// Language tabs and the number of matches
+	var tabs strings.Builder
+	for i, tab := range b.tabs {
+		name := string(tab)
+		if tab == "" {
+			name = "all"
+		}
+		if i == b.tab {
+			tabs.WriteString(reverse + bold + " " + name + " " + resetText)
+		} else {
+			tabs.WriteString(" " + name + " ")
+		}
+	}
+	count := fmt.Sprintf("%d/%d", len(b.matches), len(b.docs))
+	tabsLine, visible := truncate(tabs.String(), max(b.width-len(count)-1, 0))
+	row(0, tabsLine+resetText+strings.Repeat(" ", max(b.width-visible-len(count), 0))+dim+count)
+	// Query
+	query, _ := truncate("> "+string(b.query), b.width-1)
+	row(1, bold+query+resetText+reverse+" ")
+	// Results and preview
+	listWidth := b.width
+	if b.width >= 60 {
+		listWidth = b.width * 2 / 5
+	}
+	previewWidth := b.width - listWidth - 1
+	bodyHeight := b.bodyHeight()
+	var lines []string
+	target := 0
+	selected := b.selected()
+	if selected != nil && previewWidth > 0 {
+		preview := b.preview(selected)
+		lines = preview.lines
+		if selected.Line > 0 {
+			target = selected.Line - 1 + preview.header
+		}
+	}
+	// Show the definition a third of the way down, unless the preview is scrolled
+	top := max(min(target-bodyHeight/3+b.previewOffset, len(lines)-bodyHeight), 0)
+	b.previewOffset = top - (target - bodyHeight/3)
+	for y := range bodyHeight {
+		line := ""
+		if i := b.offset + y; i < len(b.matches) {
+			label := pad(docLabel(b.docs[b.matches[i]]), listWidth)
+			if i == b.cursor {
+				line = reverse + label + resetText
+			} else {
+				line = label
+			}
+		} else {
+			line = strings.Repeat(" ", listWidth)
+		}
+		if previewWidth > 0 {
+			line += dim + "│" + resetText
+			if i := top + y; i < len(lines) {
+				marker := " "
+				if i == target && target > 0 {
+					marker = bold + "▌" + resetText
+				}
+				code, _ := truncate(lines[i], previewWidth-1)
+				line += marker + code
+			}
+		}
+		row(y+2, line)
+	}
+	// Location of the selected document, and the keys
+	status := ""
+	if selected != nil {
+		status = selected.Path
+		if selected.Line > 0 {
+			status = fmt.Sprintf("%s:%d", selected.Path, selected.Line)
+		}
+	}
+	help := "enter open · tab language · shift-↑/↓ ctrl-f/b scroll · esc quit"
+	if utf8.RuneCountInString(status)+utf8.RuneCountInString(help)+2 <= b.width {
+		status = pad(status, b.width-utf8.RuneCountInString(help)) + help
+	}
+	status, _ = truncate(status, b.width)
+	row(b.height-1, dim+status)
+	return s.String()
+}
@@ -0,0 +1,269 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brandtg/rtfm/app/common"
+	"golang.org/x/term"
+)
+
+// How often the size of the terminal is checked while waiting for input
+const resizeInterval = 200 * time.Millisecond
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	exitScreen  = "\x1b[?25h\x1b[?1049l"
+)
+
+// Browser is a terminal UI to narrow down search documents by a query and
+// language, with a highlighted preview of the selected code. It keeps its
+// state between runs, so the search continues after viewing a document.
+type Browser struct {
+	docs []*common.SearchDocument
+	// keys are the lowercase names and packages the query is matched against
+	keys []string
+	// tabs are the languages of the documents, after "" for all of them
+	tabs    []common.Language
+	tab     int
+	query   []rune
+	matches []int
+	cursor  int
+	offset  int
+	// previewOffset scrolls the preview relative to the definition
+	previewOffset int
+	previews      map[string]filePreview
+	width         int
+	height        int
+}
+
+func NewBrowser(docs []*common.SearchDocument, query string) *Browser {
+	b := &Browser{
+		docs:     docs,
+		keys:     make([]string, len(docs)),
+		tabs:     []common.Language{""},
+		query:    []rune(query),
+		previews: make(map[string]filePreview),
+	}
+	for i, doc := range docs {
+		b.keys[i] = strings.ToLower(doc.Name + " " + doc.Package + "@" + doc.Version)
+		if !slices.Contains(b.tabs, doc.Language) {
+			b.tabs = append(b.tabs, doc.Language)
+		}
+	}
+	slices.Sort(b.tabs[1:])
+	b.filter()
+	return b
+}
+
+// filter finds the documents in the current tab that contain every word of
+// the query.
+func (b *Browser) filter() {
+	terms := strings.Fields(strings.ToLower(string(b.query)))
+	language := b.tabs[b.tab]
+	b.matches = b.matches[:0]
+	for i, doc := range b.docs {
+		if language != "" && doc.Language != language {
+			continue
+		}
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(b.keys[i], term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			b.matches = append(b.matches, i)
+		}
+	}
+	b.cursor, b.offset, b.previewOffset = 0, 0, 0
+}
+
+func (b *Browser) selected() *common.SearchDocument {
+	if b.cursor < len(b.matches) {
+		return b.docs[b.matches[b.cursor]]
+	}
+	return nil
+}
+
+// bodyHeight is the number of rows for results, between the tabs and query
+// and the status line.
+func (b *Browser) bodyHeight() int {
+	return max(b.height-3, 1)
+}
+
+// move moves the cursor, and resets the preview to the new definition.
+func (b *Browser) move(delta int) {
+	b.cursor = max(min(b.cursor+delta, len(b.matches)-1), 0)
+	b.previewOffset = 0
+	b.scroll()
+}
+
+// scroll scrolls the results to keep the cursor visible.
+func (b *Browser) scroll() {
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+b.bodyHeight() {
+		b.offset = b.cursor - b.bodyHeight() + 1
+	}
+}
+
+type action int
+
+const (
+	actionNone action = iota
+	actionSelect
+	actionCancel
+)
+
+// handle updates the state for a key.
+func (b *Browser) handle(key string) action {
+	switch key {
+	case keyEnter:
+		if b.selected() != nil {
+			return actionSelect
+		}
+	case keyEscape:
+		return actionCancel
+	case keyUp:
+		b.move(-1)
+	case keyDown:
+		b.move(1)
+	case keyPageUp:
+		b.move(-b.bodyHeight())
+	case keyPageDown:
+		b.move(b.bodyHeight())
+	case keyHome:
+		b.move(-len(b.matches))
+	case keyEnd:
+		b.move(len(b.matches))
+	case keyShiftUp:
+		b.previewOffset--
+	case keyShiftDown:
+		b.previewOffset++
+	case keyPreviewUp:
+		b.previewOffset -= b.bodyHeight() / 2
+	case keyPreviewDn:
+		b.previewOffset += b.bodyHeight() / 2
+	case keyTab, keyRight:
+		b.tab = (b.tab + 1) % len(b.tabs)
+		b.filter()
+	case keyBackTab, keyLeft:
+		b.tab = (b.tab + len(b.tabs) - 1) % len(b.tabs)
+		b.filter()
+	case keyBackspace:
+		if len(b.query) > 0 {
+			b.query = b.query[:len(b.query)-1]
+			b.filter()
+		}
+	case keyClearQuery:
+		b.query = b.query[:0]
+		b.filter()
+	case keyDeleteWord:
+		query := strings.TrimRight(string(b.query), " ")
+		b.query = []rune(query[:strings.LastIndex(query, " ")+1])
+		b.filter()
+	default:
+		if isPrintable(key) {
+			b.query = append(b.query, []rune(key)...)
+			b.filter()
+		}
+	}
+	return actionNone
+}
+
+// control runs f with the file descriptor of the terminal, without putting it
+// in blocking mode (which would disable read deadlines).
+func control(tty *os.File, f func(fd int) error) error {
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	err = conn.Control(func(fd uintptr) { ferr = f(int(fd)) })
+	if err != nil {
+		return err
+	}
+	return ferr
+}
+
+// Run shows the browser until a document is selected, or returns nil if the
+// browser is closed.
+func (b *Browser) Run() (*common.SearchDocument, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("the terminal UI needs a terminal, use --format to print the results: %w", err)
+	}
+	defer tty.Close()
+	var state *term.State
+	err = control(tty, func(fd int) error {
+		state, err = term.MakeRaw(fd)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the terminal: %w", err)
+	}
+	defer control(tty, func(fd int) error { return term.Restore(fd, state) })
+	tty.WriteString(enterScreen)
+	defer tty.WriteString(exitScreen)
+	buf := make([]byte, 256)
+	dirty := true
+	for {
+		// Redraw after input, or when the terminal is resized
+		width, height := b.width, b.height
+		err = control(tty, func(fd int) error {
+			width, height, err = term.GetSize(fd)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if dirty || width != b.width || height != b.height {
+			b.width, b.height = width, height
+			b.scroll()
+			_, err = tty.WriteString(b.render())
+			if err != nil {
+				return nil, err
+			}
+			dirty = false
+		}
+		// Wait for input (without a deadline if the terminal doesn't support one)
+		tty.SetReadDeadline(time.Now().Add(resizeInterval))
+		n, err := tty.Read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			switch b.handle(key) {
+			case actionSelect:
+				return b.selected(), nil
+			case actionCancel:
+				return nil, nil
+			}
+		}
+		dirty = true
+	}
+}
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"ab", []string{"a", "b"}},
+		{"\x1b", []string{keyEscape}},
+		{"\x1b[A\x1b[B\x1bOA", []string{keyUp, keyDown, keyUp}},
+		{"\x1b[1;2B\x1b[Z\t", []string{keyShiftDown, keyBackTab, keyTab}},
+		{"é\r\x7f", []string{"é", keyEnter, keyBackspace}},
+		// Unknown escape sequences are ignored
+		{"\x1b[15~x", []string{"x"}},
+	}
+	for _, test := range tests {
+		actual := parseKeys([]byte(test.input))
+		if !slices.Equal(actual, test.expected) {
+			t.Errorf("%q: expected %q, got %q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	line, visible := truncate("\x1b[38;5;81mexport\x1b[0m function", 8)
+	if line != "\x1b[38;5;81mexport\x1b[0m f" || visible != 8 {
+		t.Errorf("unexpected truncation: %q (%d)", line, visible)
+	}
+	if padded := pad("abc", 5); padded != "abc  " {
+		t.Errorf("unexpected padding: %q", padded)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	// A comment that spans lines keeps its color on the second line
+	lines := splitLines("\x1b[38;5;242m/* a\nb */\x1b[0m\x1b[38;5;81mint\x1b[0m x;\n")
+	expected := []string{"\x1b[38;5;242m/* a", "\x1b[38;5;242mb */\x1b[0m\x1b[38;5;81mint\x1b[0m x;"}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestBrowserFilter(t *testing.T) {
+	docs := []*common.SearchDocument{
+		{Language: "javascript", Name: "lodash/get.js", Package: "lodash", Version: "4.17.21"},
+		{Language: "javascript", Name: "lodash/get.js", Package: "lodash", Version: "3.10.1"},
+		{Language: "python", Name: "requests.get", Package: "requests", Version: "2.31.0"},
+	}
+	b := NewBrowser(docs, "")
+	b.height = 10
+	if !slices.Equal(b.tabs, []common.Language{"", "javascript", "python"}) {
+		t.Fatalf("unexpected tabs: %v", b.tabs)
+	}
+	// Every word of the query matches the name or package version
+	for _, key := range []string{"g", "e", "t", " ", "4", "."} {
+		b.handle(key)
+	}
+	if !slices.Equal(b.matches, []int{0}) {
+		t.Errorf("unexpected matches: %v", b.matches)
+	}
+	// Tabs filter by language
+	b.handle(keyClearQuery)
+	b.handle(keyBackTab)
+	if !slices.Equal(b.matches, []int{2}) || b.selected() != docs[2] {
+		t.Errorf("unexpected matches: %v", b.matches)
+	}
+	if b.handle(keyEnter) != actionSelect || b.handle(keyEscape) != actionCancel {
+		t.Error("unexpected actions")
+	}
+}
+
+func TestHighlightHeader(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.py")
+	os.WriteFile(small, []byte("def first():\n    pass\n"), 0o644)
+	large := filepath.Join(dir, "large.py")
+	os.WriteFile(large, []byte("def first():\n"+strings.Repeat("#\n", maxHighlightSize)), 0o644)
+	tests := []struct {
+		path   string
+		header int
+	}{
+		// The path is prepended to highlighted files only
+		{small, common.HighlightHeaderLines},
+		{large, 0},
+	}
+	for _, test := range tests {
+		preview := highlight(&common.SearchDocument{Language: "python", Path: test.path, Line: 1})
+		if preview.header != test.header {
+			t.Errorf("%s: expected %d header lines, got %d", test.path, test.header, preview.header)
+		}
+		if line := preview.lines[preview.header]; !strings.Contains(line, "first") {
+			t.Errorf("%s: the definition is not after the header: %q", test.path, line)
+		}
+	}
+}
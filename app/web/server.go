@@ -0,0 +1,237 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// Maximum number of results on the search page
+const maxResults = 500
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"kind":    common.NameFromKind,
+	"library": library,
+}).ParseFS(templateFiles, "templates/*.html"))
+
+// library describes the library of a document or package (e.g.
+// lodash@4.17.21).
+func library(pkg string, version string) string {
+	switch {
+	case pkg == "":
+		return "unknown"
+	case version == "":
+		return pkg
+	default:
+		return pkg + "@" + version
+	}
+}
+
+// Server serves a web UI to search and browse the index.
+type Server struct {
+	db *sql.DB
+}
+
+func NewServer(db *sql.DB) *Server {
+	return &Server{db: db}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /search", s.handleSearch)
+	mux.HandleFunc("GET /doc/{id}", s.handleDoc)
+	mux.HandleFunc("GET /packages", s.handlePackages)
+	mux.HandleFunc("GET /tree", s.handleTree)
+	return mux
+}
+
+func (s *Server) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		slog.Warn("Error rendering page", "template", name, "error", err)
+	}
+}
+
+func (s *Server) error(w http.ResponseWriter, err error, status int) {
+	if status == http.StatusInternalServerError {
+		slog.Warn("Error serving request", "error", err)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// languageSummary is a language on the index page, with its number of
+// libraries.
+type languageSummary struct {
+	Language common.Language
+	Packages int
+}
+
+// handleIndex shows the search form and the indexed languages.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	packages, err := common.ListPackages(s.db, "")
+	if err != nil {
+		s.error(w, err, http.StatusInternalServerError)
+		return
+	}
+	languages := make([]languageSummary, 0)
+	for _, pkg := range packages {
+		if len(languages) == 0 || languages[len(languages)-1].Language != pkg.Language {
+			languages = append(languages, languageSummary{Language: pkg.Language})
+		}
+		languages[len(languages)-1].Packages++
+	}
+	s.render(w, "index.html", map[string]any{"Languages": languages})
+}
+
+// handleSearch finds documents like rtfm search, as HTML or in the formats of
+// --format (text, json or jsonl).
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := common.DocumentFilter{
+		Language: common.LanguageFromName(query.Get("lang")),
+		Package:  query.Get("package"),
+		Version:  query.Get("version"),
+	}
+	match := common.MatchQuery
+	if query.Get("regex") != "" {
+		match = common.MatchRegexp
+	} else if query.Get("exact") != "" {
+		match = common.MatchLike
+	}
+	docs, err := common.FindDocuments(s.db, filter, query.Get("q"), match)
+	if err != nil {
+		s.error(w, err, http.StatusInternalServerError)
+		return
+	}
+	total := len(docs)
+	docs = docs[:min(len(docs), maxResults)]
+	switch format := query.Get("format"); format {
+	case "":
+	case common.FormatJSON, common.FormatJSONL, common.FormatText:
+		contentTypes := map[string]string{
+			common.FormatJSON:  "application/json",
+			common.FormatJSONL: "application/x-ndjson",
+			common.FormatText:  "text/plain; charset=utf-8",
+		}
+		w.Header().Set("Content-Type", contentTypes[format])
+		err = common.WriteDocuments(w, docs, format)
+		if err != nil {
+			slog.Warn("Error writing search results", "error", err)
+		}
+		return
+	default:
+		s.error(w, fmt.Errorf("unknown format: %s", format), http.StatusBadRequest)
+		return
+	}
+	s.render(w, "search.html", map[string]any{
+		"Query":  query.Get("q"),
+		"Filter": filter,
+		"Docs":   docs,
+		"Total":  total,
+	})
+}
+
+// handleDoc shows the highlighted file of a document, scrolled to its
+// definition.
+func (s *Server) handleDoc(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.error(w, err, http.StatusBadRequest)
+		return
+	}
+	doc, err := common.FindDocument(s.db, id)
+	if err != nil {
+		s.error(w, err, http.StatusNotFound)
+		return
+	}
+	code, err := common.ReadFile(doc.Path)
+	if err != nil {
+		s.error(w, err, http.StatusInternalServerError)
+		return
+	}
+	// Rank the document higher in later searches
+	err = common.RecordUse(s.db, doc)
+	if err != nil {
+		slog.Warn("Error recording selection", "error", err)
+	}
+	options := []html.Option{
+		html.WithLineNumbers(true),
+		html.WithLinkableLineNumbers(true, "L"),
+		html.TabWidth(4),
+	}
+	if doc.Line > 0 {
+		options = append(options, html.HighlightLines([][2]int{{doc.Line, doc.Line}}))
+	}
+	highlighted, err := common.HighlightCode(string(code), doc.Language, "", html.New(options...))
+	if err != nil {
+		s.error(w, err, http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "doc.html", map[string]any{
+		"Doc":  doc,
+		"Code": template.HTML(highlighted),
+	})
+}
+
+// handlePackages lists the libraries of a language.
+func (s *Server) handlePackages(w http.ResponseWriter, r *http.Request) {
+	language := common.LanguageFromName(r.URL.Query().Get("lang"))
+	if language == "" {
+		s.error(w, fmt.Errorf("unknown language: %s", r.URL.Query().Get("lang")), http.StatusNotFound)
+		return
+	}
+	packages, err := common.ListPackages(s.db, language)
+	if err != nil {
+		s.error(w, err, http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "packages.html", map[string]any{"Language": language, "Packages": packages})
+}
+
+// handleTree shows the files of a library as a tree.
+func (s *Server) handleTree(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	language := common.LanguageFromName(query.Get("lang"))
+	pkg, version := query.Get("package"), query.Get("version")
+	if language == "" {
+		s.error(w, fmt.Errorf("unknown language: %s", query.Get("lang")), http.StatusNotFound)
+		return
+	}
+	docs, err := common.FindPackageDocuments(s.db, language, pkg, version)
+	if err != nil {
+		s.error(w, err, http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "tree.html", map[string]any{
+		"Language": language,
+		"Package":  pkg,
+		"Version":  version,
+		"Tree":     buildTree(docs),
+	})
+}
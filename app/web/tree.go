@@ -0,0 +1,117 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"cmp"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// treeNode is a directory or file of a library, in the package tree.
+type treeNode struct {
+	Name string
+	// Doc is the first document of a file (the module, or its first symbol)
+	Doc      *common.SearchDocument
+	Children []*treeNode
+}
+
+// relativePath returns the path of a document's file in its root (for
+// archives, the path of the entry), or false if the file isn't in its root
+// (e.g. stubs generated from a jar).
+func relativePath(doc *common.SearchDocument) (string, bool) {
+	if archive, entry, ok := common.SplitArchivePath(doc.Path); ok && archive == doc.Root {
+		return entry, true
+	}
+	if rel, err := filepath.Rel(doc.Root, doc.Path); err == nil && doc.Root != "" && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel), true
+	}
+	return filepath.ToSlash(doc.Path), false
+}
+
+// commonDir returns the directory that contains all of the paths.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	dir := path.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for dir != "/" && dir != "." && !strings.HasPrefix(p, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	return dir
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &treeNode{Name: name}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// sort orders directories before files, then by name.
+func (n *treeNode) sort() {
+	slices.SortFunc(n.Children, func(a, b *treeNode) int {
+		if (a.Doc == nil) != (b.Doc == nil) {
+			if a.Doc == nil {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	for _, child := range n.Children {
+		child.sort()
+	}
+}
+
+// buildTree groups the files of documents by directory.
+func buildTree(docs []*common.SearchDocument) *treeNode {
+	paths := make([]string, len(docs))
+	outside := make([]string, 0)
+	for i, doc := range docs {
+		rel, ok := relativePath(doc)
+		if !ok {
+			outside = append(outside, rel)
+		}
+		paths[i] = rel
+	}
+	// Files outside of their root are shown relative to their shared directory
+	dir := commonDir(outside)
+	root := &treeNode{}
+	for i, doc := range docs {
+		if dir != "" && strings.HasPrefix(paths[i], dir+"/") {
+			paths[i] = strings.TrimPrefix(paths[i], dir+"/")
+		}
+		node := root
+		for _, part := range strings.Split(strings.Trim(paths[i], "/"), "/") {
+			node = node.child(part)
+		}
+		if node.Doc == nil || doc.Line < node.Doc.Line {
+			node.Doc = doc
+		}
+	}
+	root.sort()
+	return root
+}
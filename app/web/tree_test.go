@@ -0,0 +1,65 @@
+package web
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brandtg/rtfm/app/common"
+)
+
+// printTree renders a tree as indented names, with the line of each file.
+func printTree(b *strings.Builder, node *treeNode, indent string) {
+	for _, child := range node.Children {
+		b.WriteString(indent + child.Name)
+		if child.Doc != nil {
+			b.WriteString(":" + strconv.Itoa(child.Doc.Line))
+		}
+		b.WriteString("\n")
+		printTree(b, child, indent+"  ")
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	jar := "/m2/gadget-2.0-sources.jar"
+	tests := []struct {
+		name string
+		docs []*common.SearchDocument
+		want string
+	}{
+		{
+			name: "directory",
+			docs: []*common.SearchDocument{
+				{Path: "/nm/lodash/get.js", Root: "/nm", Line: 0},
+				{Path: "/nm/lodash/get.js", Root: "/nm", Line: 4},
+				{Path: "/nm/lodash/fp/map.js", Root: "/nm", Line: 2},
+				{Path: "/nm/lodash/index.js", Root: "/nm"},
+			},
+			want: "lodash\n  fp\n    map.js:2\n  get.js:0\n  index.js:0\n",
+		},
+		{
+			name: "archive",
+			docs: []*common.SearchDocument{
+				{Path: common.ArchivePath(jar, "com/example/Gadget.java"), Root: jar, Line: 3},
+			},
+			want: "com\n  example\n    Gadget.java:3\n",
+		},
+		{
+			name: "outside root",
+			docs: []*common.SearchDocument{
+				{Path: "/stubs/widget/com/example/Widget.java", Root: "/m2/widget.jar", Line: 5},
+				{Path: "/stubs/widget/com/Util.java", Root: "/m2/widget.jar", Line: 1},
+			},
+			want: "example\n  Widget.java:5\nUtil.java:1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			printTree(&b, buildTree(test.docs), "")
+			if b.String() != test.want {
+				t.Errorf("buildTree() =\n%s\nwant\n%s", b.String(), test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/brandtg/rtfm/app/common"
+	"github.com/spf13/cobra"
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep <terms>...",
+	Short: "Search the contents of indexed code",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Parse arguments
+		langName, err := cmd.Flags().GetString("lang")
+		if err != nil {
+			panic(err)
+		}
+		lang := common.LanguageFromName(langName)
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			panic(err)
+		}
+		maxLines, err := cmd.Flags().GetInt("lines")
+		if err != nil {
+			panic(err)
+		}
+		// Open the database
+		db, err := common.OpenDB()
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		// Search the file contents, best matches first
+		matches, err := common.FindContent(db, lang, args, limit, maxLines)
+		if err != nil {
+			panic(err)
+		}
+		for _, match := range matches {
+			fmt.Printf("%s\t%s\n", match.Language, match.Path)
+			for _, line := range match.Lines {
+				fmt.Printf("%6d: %s\n", line.Number, line.Text)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(grepCmd)
+	grepCmd.Flags().StringP("lang", "l", "", "Language to search for")
+	grepCmd.Flags().IntP("limit", "n", 50, "Maximum number of files to show")
+	grepCmd.Flags().Int("lines", 5, "Maximum number of matching lines to show per file")
+}
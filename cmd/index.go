@@ -15,11 +15,13 @@
 package cmd
 
 import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+
 	"github.com/brandtg/rtfm/app/common"
-	"github.com/brandtg/rtfm/app/golang"
-	"github.com/brandtg/rtfm/app/java"
-	"github.com/brandtg/rtfm/app/javascript"
-	"github.com/brandtg/rtfm/app/python"
 	"github.com/spf13/cobra"
 )
 
@@ -36,45 +38,82 @@ var indexCmd = &cobra.Command{
 		if err != nil {
 			panic(err)
 		}
-		if remove {
-			err = common.RemoveOutputDir()
-			if err != nil {
-				panic(err)
-			}
+		full, err := cmd.Flags().GetBool("full")
+		if err != nil {
+			panic(err)
 		}
-		// Java
-		if langName == "" || langName == "java" {
-			err = java.Index()
-			if err != nil {
-				panic(err)
-			}
+		jobs, err := cmd.Flags().GetInt("jobs")
+		if err != nil {
+			panic(err)
+		}
+		projectDir, err := cmd.Flags().GetString("project")
+		if err != nil {
+			panic(err)
 		}
-		// Python
-		if langName == "" || langName == "python" {
-			err = python.Index()
+		opts := common.IndexOptions{Full: full, Jobs: jobs}
+		if projectDir != "" {
+			opts.Project, err = common.LoadProject(projectDir)
 			if err != nil {
 				panic(err)
 			}
+			slog.Info("Indexing project dependencies", "project", opts.Project.Dir,
+				"dependencies", len(opts.Project.Dependencies))
 		}
-		// JavaScript
-		if langName == "" || langName == "javascript" {
-			err = javascript.Index()
+		if remove {
+			err = common.RemoveOutputDir()
 			if err != nil {
 				panic(err)
 			}
 		}
-		// Go
-		if langName == "" || langName == "go" {
-			err = golang.Index()
+		// Index each language (or only the one given)
+		found := false
+		for _, indexer := range common.Indexers() {
+			if langName != "" && string(indexer.Language()) != strings.ToLower(langName) {
+				continue
+			}
+			found = true
+			err = common.Index(indexer, opts)
 			if err != nil {
 				panic(err)
 			}
 		}
+		if !found {
+			panic(fmt.Errorf("unknown language: %s", langName))
+		}
+		// Report archive entries that were not indexed
+		reportSkippedEntries(common.SkippedArchiveEntries())
 	},
 }
 
+func reportSkippedEntries(skipped []common.SkippedEntry) {
+	counts := make(map[string]map[string]int)
+	archives := make([]string, 0)
+	for _, entry := range skipped {
+		slog.Debug("Skipped archive entry", "archive", entry.Archive, "entry", entry.Entry, "reason", entry.Reason)
+		if _, ok := counts[entry.Archive]; !ok {
+			counts[entry.Archive] = make(map[string]int)
+			archives = append(archives, entry.Archive)
+		}
+		counts[entry.Archive][entry.Reason]++
+	}
+	for _, archive := range archives {
+		reasons := make([]string, 0)
+		for reason, count := range counts[archive] {
+			reasons = append(reasons, fmt.Sprintf("%s: %d", reason, count))
+		}
+		slices.Sort(reasons)
+		slog.Warn("Skipped archive entries", "archive", archive, "reasons", strings.Join(reasons, ", "))
+	}
+	if len(skipped) > 0 {
+		slog.Warn("Skipped unsafe or oversized archive entries", "count", len(skipped), "archives", len(archives))
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(indexCmd)
 	indexCmd.Flags().StringP("lang", "l", "", "Language to index")
 	indexCmd.Flags().Bool("remove", false, "Remove any existing index")
+	indexCmd.Flags().Bool("full", false, "Re-parse every file, even if it has not changed")
+	indexCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of roots and files to process in parallel")
+	indexCmd.Flags().String("project", "", "Only index the libraries used by the project in this directory")
 }
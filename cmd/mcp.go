@@ -0,0 +1,51 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/brandtg/rtfm/app/common"
+	"github.com/brandtg/rtfm/app/mcp"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run a Model Context Protocol server over stdio that lets coding assistants search indexed libraries",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectDir, err := cmd.Flags().GetString("project")
+		if err != nil {
+			panic(err)
+		}
+		var project *common.Project
+		if projectDir != "" {
+			project, err = common.LoadProject(projectDir)
+			if err != nil {
+				panic(err)
+			}
+		}
+		err = mcp.Serve(os.Stdin, os.Stdout, project)
+		if err != nil {
+			panic(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+	mcpCmd.Flags().String("project", "", "Only search the libraries used by the project in this directory")
+}
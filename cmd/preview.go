@@ -0,0 +1,63 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/brandtg/rtfm/app/common"
+	"github.com/spf13/cobra"
+)
+
+var previewCmd = &cobra.Command{
+	Use:    "preview <id>",
+	Short:  "Print the highlighted code of an indexed document (used by the fzf preview)",
+	Args:   cobra.ExactArgs(1),
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Parse arguments
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		// Open the database
+		db, err := common.OpenDB()
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		// Find the document
+		doc, err := common.FindDocument(db, id)
+		if err != nil {
+			panic(err)
+		}
+		// Read the code from the file (or the archive it is in)
+		code, err := common.ReadFile(doc.Path)
+		if err != nil {
+			panic(err)
+		}
+		// Highlight the code
+		highlightedCode, err := common.HighlightCode(string(code), doc.Language, doc.Path, common.TerminalFormatter)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Print(highlightedCode)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+}
@@ -15,29 +15,81 @@
 package cmd
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 
 	"github.com/brandtg/rtfm/app/common"
+	"github.com/brandtg/rtfm/app/tui"
 	"github.com/spf13/cobra"
 )
 
 var searchCmd = &cobra.Command{
-	Use:   "search",
+	Use:   "search <query>...",
 	Short: "Search for code snippets",
-	Args:  cobra.ExactArgs(1),
+	Long: `Search for code snippets by name.
+
+Terms match names that contain them, or whose words they abbreviate (cfgLdr
+matches ConfigLoader). Queries can also contain lang:, pkg:, version: and
+kind: qualifiers, -term to exclude names and /regex/ to match names with a
+regular expression, e.g.
+
+  rtfm search -- 'CHM kind:class -test'`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Parse arguments
-		query := args[0]
+		query := strings.Join(args, " ")
 		langName, err := cmd.Flags().GetString("lang")
 		if err != nil {
 			panic(err)
 		}
 		lang := common.LanguageFromName(langName)
+		pkg, err := cmd.Flags().GetString("package")
+		if err != nil {
+			panic(err)
+		}
+		version, err := cmd.Flags().GetString("version")
+		if err != nil {
+			panic(err)
+		}
 		exact, err := cmd.Flags().GetBool("exact")
 		if err != nil {
 			panic(err)
 		}
+		regex, err := cmd.Flags().GetBool("regex")
+		if err != nil {
+			panic(err)
+		}
+		useEditor, err := cmd.Flags().GetBool("editor")
+		if err != nil {
+			panic(err)
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			panic(err)
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			panic(err)
+		}
+		ui, err := cmd.Flags().GetString("ui")
+		if err != nil {
+			panic(err)
+		}
+		projectDir, err := cmd.Flags().GetString("project")
+		if err != nil {
+			panic(err)
+		}
+		var project *common.Project
+		if projectDir != "" {
+			project, err = common.LoadProject(projectDir)
+			if err != nil {
+				panic(err)
+			}
+		}
 		// Open the database
 		db, err := common.OpenDB()
 		if err != nil {
@@ -45,25 +97,69 @@ var searchCmd = &cobra.Command{
 		}
 		defer db.Close()
 		// Search for code snippets
-		docs, err := common.FindDocuments(db, lang, query, exact)
+		filter := common.DocumentFilter{Language: lang, Package: pkg, Version: version}
+		match := common.MatchQuery
+		if regex {
+			match = common.MatchRegexp
+		} else if exact {
+			match = common.MatchLike
+		}
+		docs, err := common.FindDocuments(db, filter, query, match)
 		if err != nil {
 			panic(err)
 		}
+		// Only keep the library versions the project uses
+		if project != nil {
+			docs = slices.DeleteFunc(docs, func(doc *common.SearchDocument) bool { return !project.Includes(doc) })
+		}
+		if limit > 0 && len(docs) > limit {
+			docs = docs[:limit]
+		}
+		// Print the results for scripts and editors
+		if format != "" {
+			err = common.WriteDocuments(os.Stdout, docs, format)
+			if err != nil {
+				panic(err)
+			}
+			return
+		}
 		// Interactive loop to select and view code files
-		var filterQuery string
-		var selected *common.SearchDocument
+		selectDocument, err := newSelector(ui, docs)
+		if err != nil {
+			panic(err)
+		}
 		for {
 			// Select the code by name
-			filterQuery, selected, err = common.RunFzfSearchDocuments(filterQuery, docs)
+			selected, key, err := selectDocument()
 			if err != nil {
-				// If fzf was closed just exit cleanly
-				if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 130 {
-					break
-				}
 				panic(err)
 			}
-			// Read the code from the file
-			code, err := os.ReadFile(selected.Path)
+			if selected == nil {
+				break
+			}
+			// Rank the selection higher in later searches
+			err = common.RecordUse(db, selected)
+			if err != nil {
+				slog.Warn("Error recording selection", "error", err)
+			}
+			// Copy the path of the file
+			if key == common.FzfKeyCopy {
+				err = common.CopyToClipboard(selected.Path)
+				if err != nil {
+					panic(err)
+				}
+				continue
+			}
+			// Open the file in the user's editor
+			if useEditor || key == common.FzfKeyEditor {
+				err = common.OpenInEditor(selected.Path, selected.Line)
+				if err != nil {
+					panic(err)
+				}
+				continue
+			}
+			// Read the code from the file (or the archive it is in)
+			code, err := common.ReadFile(selected.Path)
 			if err != nil {
 				panic(err)
 			}
@@ -72,18 +168,60 @@ var searchCmd = &cobra.Command{
 				string(code),
 				selected.Language,
 				selected.Path,
+				common.TerminalFormatter,
 			)
 			if err != nil {
 				panic(err)
 			}
-			// Display the code in a pager
-			common.DisplayInPager(highlightedCode)
+			// Display the code in a pager, starting at the definition
+			line := 0
+			if selected.Line > 0 {
+				line = selected.Line + common.HighlightHeaderLines
+			}
+			common.DisplayInPager(highlightedCode, line)
 		}
 	},
 }
 
+// newSelector returns a function that lets the user select a document in the
+// terminal UI or fzf (and the key it was selected with), which returns nil once
+// they quit.
+func newSelector(ui string, docs []*common.SearchDocument) (func() (*common.SearchDocument, string, error), error) {
+	switch ui {
+	case "tui":
+		browser := tui.NewBrowser(docs, "")
+		return func() (*common.SearchDocument, string, error) {
+			selected, err := browser.Run()
+			return selected, "", err
+		}, nil
+	case "fzf":
+		var filterQuery string
+		return func() (*common.SearchDocument, string, error) {
+			var selected *common.SearchDocument
+			var key string
+			var err error
+			filterQuery, key, selected, err = common.RunFzfSearchDocuments(filterQuery, docs)
+			// If fzf was closed just exit cleanly
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 130 {
+				return nil, "", nil
+			}
+			return selected, key, err
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown UI: %s (expected tui or fzf)", ui)
+}
+
 func init() {
 	rootCmd.AddCommand(searchCmd)
 	searchCmd.Flags().StringP("lang", "l", "", "Language to search for")
-	searchCmd.Flags().BoolP("exact", "e", false, "Exact match")
+	searchCmd.Flags().StringP("package", "p", "", "Only search libraries whose name contains this")
+	searchCmd.Flags().String("version", "", "Only search this library version (or versions starting with it)")
+	searchCmd.Flags().BoolP("exact", "e", false, "Match the query as a SQL LIKE pattern instead")
+	searchCmd.Flags().BoolP("regex", "r", false, "Match the query as a regular expression instead")
+	searchCmd.MarkFlagsMutuallyExclusive("exact", "regex")
+	searchCmd.Flags().String("ui", "tui", "Select results in the built-in terminal UI (tui) or fzf")
+	searchCmd.Flags().Bool("editor", false, "Open the selected code in $EDITOR instead of a pager")
+	searchCmd.Flags().StringP("format", "f", "", "Print the results as text, json or jsonl instead of selecting one with fzf")
+	searchCmd.Flags().IntP("limit", "n", 0, "Maximum number of results (0 for no limit)")
+	searchCmd.Flags().String("project", "", "Only search the libraries used by the project in this directory")
 }
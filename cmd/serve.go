@@ -0,0 +1,66 @@
+// Copyright 2025 Greg Brandt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/brandtg/rtfm/app/common"
+	"github.com/brandtg/rtfm/app/web"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a web UI to search and browse indexed code",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		addr, err := cmd.Flags().GetString("addr")
+		if err != nil {
+			panic(err)
+		}
+		// Open the database
+		db, err := common.OpenDB()
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		// Listen first, so the address is known when the port is 0
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			panic(err)
+		}
+		slog.Info("Serving", "url", "http://"+listener.Addr().String())
+		server := &http.Server{
+			Handler:           web.NewServer(db).Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		}
+		err = server.Serve(listener)
+		if err != nil {
+			panic(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on")
+}
@@ -6,10 +6,12 @@ require (
 	github.com/alecthomas/chroma/v2 v2.15.0
 	github.com/mattn/go-sqlite3 v1.14.24
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/term v0.32.0
 )
 
 require (
 	github.com/dlclark/regexp2 v1.11.4 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
+	golang.org/x/sys v0.33.0 // indirect
 )